@@ -0,0 +1,133 @@
+package chronos
+
+import (
+	"sync"
+	"time"
+)
+
+// Outcome is the result of an operation which is reported to an `Adaptive` limiter.
+type Outcome uint8
+
+const (
+	// OutcomeSuccess is an operation which is completed, the limit may increase.
+	OutcomeSuccess Outcome = iota
+	// OutcomeThrottled is an operation which is throttled by the remote service,
+	// i.e a 429 status code, the limit decreases.
+	OutcomeThrottled
+	// OutcomeTimeout is an operation which timed out, the limit decreases.
+	OutcomeTimeout
+	// OutcomeIgnored is an operation which is not a signal, i.e a 404 status code.
+	OutcomeIgnored
+)
+
+// String returns the name of the outcome.
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeThrottled:
+		return "throttled"
+	case OutcomeTimeout:
+		return "timeout"
+	default:
+		return "ignored"
+	}
+}
+
+// Adaptive is a limiter which finds the limit of a remote service that doesn't publish it.
+// It increases its "max" operations additively on success,
+// by `Increase` per a full circle of successful operations,
+// and it decreases it multiplicatively, by the `Decrease` factor,
+// on a throttling signal, at most once per circle,
+// so the operations of the same burst don't collapse it.
+// The limit is kept between the `Floor` and the `Ceiling`.
+//
+// Use the `NewAdaptive` function.
+type Adaptive struct {
+	*C
+
+	Floor   uint32
+	Ceiling uint32
+	// Increase is the additive increase of the limit per a circle of successful operations,
+	// defaults to 1.
+	Increase float64
+	// Decrease is the multiplicative decrease of the limit on a throttling signal,
+	// defaults to 0.5.
+	Decrease float64
+
+	mu           sync.Mutex
+	limit        float64
+	lastDecrease time.Time
+}
+
+// NewAdaptive returns a new adaptive limiter which starts with "initial" operations
+// "per" time duration and adapts its limit between the "floor" and the "ceiling".
+func NewAdaptive(initial, floor, ceiling uint32, per time.Duration) *Adaptive {
+	a := &Adaptive{
+		C:        New(initial, per),
+		Floor:    floor,
+		Ceiling:  ceiling,
+		Increase: 1,
+		Decrease: 0.5,
+		limit:    float64(initial),
+	}
+	a.bound()
+	a.C.Max = uint32(a.limit)
+	return a
+}
+
+// bound keeps the limit between the floor and the ceiling, it must be called under lock.
+func (a *Adaptive) bound() {
+	if a.limit < float64(a.Floor) {
+		a.limit = float64(a.Floor)
+	}
+	if a.Ceiling > 0 && a.limit > float64(a.Ceiling) {
+		a.limit = float64(a.Ceiling)
+	}
+	if a.limit < 1 {
+		a.limit = 1
+	}
+}
+
+// Report reports the outcome of an operation, the limit is adapted to it.
+func (a *Adaptive) Report(o Outcome) {
+	a.report(o, time.Now())
+}
+
+func (a *Adaptive) report(o Outcome, now time.Time) {
+	a.C.mu.RLock()
+	period, per := a.C.period(now.UnixNano()), time.Duration(a.C.Per)
+	a.C.mu.RUnlock()
+
+	a.mu.Lock()
+	old := uint32(a.limit)
+
+	switch o {
+	case OutcomeSuccess:
+		a.limit += a.Increase / a.limit
+	case OutcomeThrottled, OutcomeTimeout:
+		if !a.lastDecrease.IsZero() && now.Sub(a.lastDecrease) < period {
+			break
+		}
+		a.lastDecrease = now
+		a.limit *= a.Decrease
+	default:
+		a.mu.Unlock()
+		return
+	}
+
+	a.bound()
+	// under lock, so the limits are set in order.
+	if limit := uint32(a.limit); limit != old {
+		a.C.SetLimit(limit, per)
+	}
+	a.mu.Unlock()
+}
+
+// Limit returns the current limit of operations per circle.
+func (a *Adaptive) Limit() uint32 {
+	a.mu.Lock()
+	limit := uint32(a.limit)
+	a.mu.Unlock()
+	return limit
+}
@@ -0,0 +1,39 @@
+package chronos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptive(t *testing.T) {
+	a := NewAdaptive(10, 4, 12, time.Minute)
+	now := time.Now()
+
+	// additive increase, about one per a circle of successful operations.
+	for i := 0; i < 11; i++ {
+		a.report(OutcomeSuccess, now)
+	}
+	if limit := a.Limit(); limit != 11 || a.Stats().Max != 11 {
+		t.Fatalf("expected limit of 11 but got %d", limit)
+	}
+
+	// multiplicative decrease, once per circle.
+	a.report(OutcomeThrottled, now)
+	a.report(OutcomeTimeout, now.Add(time.Second))
+	if limit := a.Limit(); limit != 5 || a.Stats().Max != 5 {
+		t.Fatalf("expected limit of 5 but got %d", limit)
+	}
+
+	a.report(OutcomeThrottled, now.Add(time.Minute))
+	if limit := a.Limit(); limit != 4 {
+		t.Fatalf("expected limit to stop at the floor of 4 but got %d", limit)
+	}
+
+	a.report(OutcomeIgnored, now)
+	for i := 0; i < 100; i++ {
+		a.report(OutcomeSuccess, now)
+	}
+	if limit := a.Limit(); limit != 12 || a.Stats().Max != 12 {
+		t.Fatalf("expected limit to stop at the ceiling of 12 but got %d", limit)
+	}
+}
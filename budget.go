@@ -0,0 +1,358 @@
+package chronos
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInsufficientBalance is returned when a `Budget` has not enough balance for the operations.
+var ErrInsufficientBalance = errors.New("chronos: insufficient balance")
+
+// Budget is a limiter of a finite balance, it doesn't refill by time like the `C`.
+// Its balance is made of prepaid credits, which never expire and are
+// increased by `TopUp`, and an optional quota which is granted on each
+// circle of a `Window`, i.e monthly, which its unused part is carried over
+// to the next circle up to a rollover cap.
+// The quota is spent first.
+//
+// The Budget is a `Snapshotable`, so its balance can survive restarts, see `Snapshotter`.
+//
+// Use the `NewBudget` or the `NewQuota` function.
+type Budget struct {
+	// Quota is the balance granted on each circle of the Window.
+	Quota uint64
+	// Window is the circle of the Quota, nil when there is no quota.
+	Window Window
+	// Rollover is the maximum unused quota that it's carried over to the next circle.
+	Rollover uint64
+
+	mu      sync.Mutex
+	quota   uint64 // the remaining quota of the current circle.
+	credits uint64
+	start   int64 // the start of the current circle, unix nanoseconds.
+
+	spent    uint64
+	rejected uint64
+
+	lowThreshold uint64
+	onLow        func(b *Budget, balance uint64)
+	low          bool // the onLow is called and not re-armed yet.
+
+	changed chan struct{} // closed when the balance is increased.
+}
+
+// NewBudget returns a new budget of "credits" prepaid credits.
+func NewBudget(credits uint64) *Budget {
+	return &Budget{
+		credits: credits,
+		changed: make(chan struct{}),
+	}
+}
+
+// NewQuota returns a new budget which grants "quota" on each circle of the "w" window,
+// up to "rollover" of the unused quota is carried over to the next circle.
+//
+// Example Code:
+//
+//	b := chronos.NewQuota(10000, chronos.Monthly(1, time.UTC), 2000)
+func NewQuota(quota uint64, w Window, rollover uint64) *Budget {
+	b := NewBudget(0)
+	b.Quota = quota
+	b.Window = w
+	b.Rollover = rollover
+	b.quota = quota
+	start, _ := w.Bounds(time.Now())
+	b.start = start.UnixNano()
+	return b
+}
+
+// OnLowBalance registers the "fn" to be called when the balance falls below the "threshold",
+// it's called again only after the balance is increased to the "threshold" or more.
+// It's called outside of the budget's lock, it should not block.
+func (b *Budget) OnLowBalance(threshold uint64, fn func(b *Budget, balance uint64)) {
+	b.mu.Lock()
+	b.lowThreshold = threshold
+	b.onLow = fn
+	b.low = false
+	b.mu.Unlock()
+}
+
+// roll grants the quota of the circles passed until the "now",
+// it should be called under the lock.
+func (b *Budget) roll(now time.Time) {
+	if b.Window == nil {
+		return
+	}
+
+	start, _ := b.Window.Bounds(now)
+	if start.UnixNano() <= b.start {
+		return
+	}
+
+	// carry over the unused quota of each circle passed,
+	// after a few circles it's always the rollover cap.
+	for circle := time.Unix(0, b.start); circle.Before(start); {
+		carried := b.quota
+		if carried > b.Rollover {
+			carried = b.Rollover
+		}
+		b.quota = carried + b.Quota
+		if carried == b.Rollover {
+			break
+		}
+		_, circle = b.Window.Bounds(circle)
+	}
+
+	b.start = start.UnixNano()
+	b.increased()
+}
+
+// increased should be called under the lock when the balance is increased.
+func (b *Budget) increased() {
+	if b.low && b.quota+b.credits >= b.lowThreshold {
+		b.low = false
+	}
+
+	if b.changed != nil {
+		close(b.changed)
+	}
+	b.changed = make(chan struct{})
+}
+
+// take spends "n" of the balance, if it's enough,
+// a "wait" call is not counted as rejected.
+func (b *Budget) take(n uint64, now time.Time, wait bool) (ok bool, changed <-chan struct{}, retryAfter time.Duration) {
+	b.mu.Lock()
+	b.roll(now)
+
+	if balance := b.quota + b.credits; balance < n {
+		if !wait {
+			b.rejected += n
+		}
+		changed = b.changed
+		if b.Window != nil {
+			_, end := b.Window.Bounds(now)
+			retryAfter = end.Sub(now)
+		}
+		b.mu.Unlock()
+		return false, changed, retryAfter
+	}
+
+	if b.quota >= n {
+		b.quota -= n
+	} else {
+		b.credits -= n - b.quota
+		b.quota = 0
+	}
+	b.spent += n
+
+	balance := b.quota + b.credits
+	onLow := b.onLow
+	notify := onLow != nil && !b.low && balance < b.lowThreshold
+	if notify {
+		b.low = true
+	}
+	b.mu.Unlock()
+
+	if notify {
+		onLow(b, balance)
+	}
+	return true, nil, 0
+}
+
+// Allow reports whether an operation is allowed now, it spends 1 of the balance if so.
+func (b *Budget) Allow() bool {
+	return b.AllowN(1)
+}
+
+// AllowN is like `Allow` but for "n" operations at once.
+func (b *Budget) AllowN(n uint64) bool {
+	ok, _, _ := b.take(n, time.Now(), false)
+	return ok
+}
+
+// Take is like `Allow` but it returns an error instead, see `TakeN`.
+func (b *Budget) Take() error {
+	return b.TakeN(1)
+}
+
+// TakeN spends "n" of the balance,
+// it returns `ErrInsufficientBalance` if the balance is not enough.
+func (b *Budget) TakeN(n uint64) error {
+	if ok, _, _ := b.take(n, time.Now(), false); !ok {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// Wait blocks until an operation is allowed or the "ctx" is done, see `WaitN`.
+func (b *Budget) Wait(ctx context.Context) error {
+	return b.WaitN(ctx, 1)
+}
+
+// WaitN blocks until "n" of the balance are spent or the "ctx" is done,
+// the balance is increased by a `TopUp` or by the quota of the next circle.
+func (b *Budget) WaitN(ctx context.Context, n uint64) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ok, changed, retryAfter := b.take(n, time.Now(), true)
+		if ok {
+			return nil
+		}
+
+		// prepaid credits only, wait for a top up.
+		if retryAfter == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-changed:
+			}
+			continue
+		}
+
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-changed:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// TopUp adds "credits" prepaid credits to the balance.
+func (b *Budget) TopUp(credits uint64) {
+	b.mu.Lock()
+	b.credits += credits
+	b.increased()
+	b.mu.Unlock()
+}
+
+// Balance returns the balance that can be spent now, the quota and the credits.
+func (b *Budget) Balance() uint64 {
+	b.mu.Lock()
+	b.roll(time.Now())
+	balance := b.quota + b.credits
+	b.mu.Unlock()
+	return balance
+}
+
+// BudgetStats is a snapshot of a budget's state.
+type BudgetStats struct {
+	Quota   uint64 `json:"quota"`   // the remaining quota of the current circle, including the carried over.
+	Credits uint64 `json:"credits"` // the remaining prepaid credits.
+	Balance uint64 `json:"balance"` // the quota and the credits.
+	// ResetAfter is when the next quota will be granted, zero when there is no quota.
+	ResetAfter time.Duration `json:"resetAfter"`
+
+	Spent    uint64 `json:"spent"`    // cumulative.
+	Rejected uint64 `json:"rejected"` // cumulative.
+}
+
+// Stats returns a snapshot of the budget's state.
+func (b *Budget) Stats() BudgetStats {
+	now := time.Now()
+
+	b.mu.Lock()
+	b.roll(now)
+	st := BudgetStats{
+		Quota:    b.quota,
+		Credits:  b.credits,
+		Balance:  b.quota + b.credits,
+		Spent:    b.spent,
+		Rejected: b.rejected,
+	}
+	if b.Window != nil {
+		_, end := b.Window.Bounds(now)
+		st.ResetAfter = end.Sub(now)
+	}
+	b.mu.Unlock()
+	return st
+}
+
+// budgetState is the persistent part of a Budget.
+type budgetState struct {
+	Quota   uint64 `json:"quota"`
+	Credits uint64 `json:"credits"`
+	Start   int64  `json:"start"` // unix nanoseconds.
+}
+
+const (
+	budgetStateVersion    byte = 1
+	budgetStateBinarySize      = 1 + 8 + 8 + 8
+)
+
+var _ Snapshotable = (*Budget)(nil)
+
+func (b *Budget) getState() budgetState {
+	b.mu.Lock()
+	s := budgetState{Quota: b.quota, Credits: b.credits, Start: b.start}
+	b.mu.Unlock()
+	return s
+}
+
+// setState restores the "s" to the budget,
+// the quota of the circles passed in the meanwhile is granted on the next call.
+func (b *Budget) setState(s budgetState) {
+	b.mu.Lock()
+	b.quota = s.Quota
+	b.credits = s.Credits
+	if b.Window != nil {
+		b.start = s.Start
+	}
+	b.increased()
+	b.mu.Unlock()
+}
+
+// MarshalBinary implements the `encoding.BinaryMarshaler` interface.
+// It encodes the balance of the budget.
+func (b *Budget) MarshalBinary() ([]byte, error) {
+	s := b.getState()
+
+	data := make([]byte, budgetStateBinarySize)
+	data[0] = budgetStateVersion
+	binary.BigEndian.PutUint64(data[1:], s.Quota)
+	binary.BigEndian.PutUint64(data[9:], s.Credits)
+	binary.BigEndian.PutUint64(data[17:], uint64(s.Start))
+	return data, nil
+}
+
+// UnmarshalBinary implements the `encoding.BinaryUnmarshaler` interface.
+// It restores a balance which was encoded by `MarshalBinary`.
+func (b *Budget) UnmarshalBinary(data []byte) error {
+	if len(data) != budgetStateBinarySize || data[0] != budgetStateVersion {
+		return ErrInvalidState
+	}
+
+	b.setState(budgetState{
+		Quota:   binary.BigEndian.Uint64(data[1:]),
+		Credits: binary.BigEndian.Uint64(data[9:]),
+		Start:   int64(binary.BigEndian.Uint64(data[17:])),
+	})
+	return nil
+}
+
+// MarshalJSON implements the `json.Marshaler` interface.
+func (b *Budget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.getState())
+}
+
+// UnmarshalJSON implements the `json.Unmarshaler` interface.
+func (b *Budget) UnmarshalJSON(data []byte) error {
+	var s budgetState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	b.setState(s)
+	return nil
+}
@@ -0,0 +1,122 @@
+package chronos
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBudget(t *testing.T) {
+	b := NewBudget(3)
+
+	var lows []uint64
+	b.OnLowBalance(2, func(_ *Budget, balance uint64) {
+		lows = append(lows, balance)
+	})
+
+	if !b.AllowN(2) {
+		t.Fatalf("expected 2 operations to be allowed")
+	}
+	if err := b.TakeN(2); err != ErrInsufficientBalance {
+		t.Fatalf("expected ErrInsufficientBalance but got %v", err)
+	}
+	if !b.Allow() || b.Allow() {
+		t.Fatalf("expected only the last credit to be allowed")
+	}
+
+	if len(lows) != 1 || lows[0] != 1 {
+		t.Fatalf("expected one low balance alert of 1 but got %v", lows)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- b.Wait(context.Background())
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	b.TopUp(5)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected wait to be allowed after a top up")
+	}
+
+	if st := b.Stats(); st.Balance != 4 || st.Credits != 4 || st.Spent != 4 || st.Rejected != 3 {
+		t.Fatalf("unexpected stats: %#+v", st)
+	}
+
+	// re-armed by the top up.
+	b.AllowN(3)
+	if len(lows) != 2 || lows[1] != 1 {
+		t.Fatalf("expected a second low balance alert of 1 but got %v", lows)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.WaitN(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatalf("expected context deadline but got %v", err)
+	}
+}
+
+func TestBudgetRollover(t *testing.T) {
+	w := NewFixed(time.Hour, 0)
+	b := NewQuota(10, w, 4)
+	b.TopUp(1)
+
+	now := time.Now()
+	if ok, _, retryAfter := b.take(8, now, false); !ok || retryAfter != 0 {
+		t.Fatalf("expected 8 operations to be allowed")
+	}
+	if ok, _, retryAfter := b.take(4, now, false); ok || retryAfter <= 0 || retryAfter > time.Hour {
+		t.Fatalf("expected 4 operations to not be allowed until the next circle but got %s", retryAfter)
+	}
+
+	// the unused 2 are carried over.
+	if ok, _, _ := b.take(13, now.Add(time.Hour), false); !ok {
+		t.Fatalf("expected the quota, the carried over and the credit to be allowed")
+	}
+	if b.quota != 0 || b.credits != 0 {
+		t.Fatalf("expected the balance to be spent but got quota: %d, credits: %d", b.quota, b.credits)
+	}
+
+	// nothing used, the carried over is capped.
+	b.roll(now.Add(5 * time.Hour))
+	if b.quota != 14 {
+		t.Fatalf("expected quota to be the rollover cap plus the quota but got %d", b.quota)
+	}
+}
+
+func TestBudgetState(t *testing.T) {
+	b := NewQuota(10, Monthly(1, time.UTC), 0)
+	b.TopUp(7)
+	b.AllowN(3)
+
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewQuota(10, Monthly(1, time.UTC), 0)
+	if err = restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.getState(); got != b.getState() {
+		t.Fatalf("binary: expected state %#+v but got %#+v", b.getState(), got)
+	}
+
+	if data, err = json.Marshal(b); err != nil {
+		t.Fatal(err)
+	}
+
+	restored = NewBudget(0)
+	if err = json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if st := restored.Stats(); st.Quota != 7 || st.Credits != 7 {
+		t.Fatalf("json: unexpected stats: %#+v", st)
+	}
+}
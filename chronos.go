@@ -1,6 +1,8 @@
 package chronos
 
 import (
+	"context"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +17,33 @@ type C struct { // C is actually a kinda of rate limiter :)
 	Max uint32 // maximum operations
 	Per int64  // per x time (in nanoseconds).
 
+	// WaitBuckets are the buckets of the wait durations histogram,
+	// defaults to the `DefaultWaitBuckets`. See `Stats` and `ResetWaits`.
+	WaitBuckets []time.Duration
+
+	// QueueSize is the maximum operations that can wait at the same time
+	// through `Wait`, the next ones fail with `ErrQueueFull`.
+	// Zero means unlimited. The `Acquire` and `Reserve` are not limited by it.
+	QueueSize uint32
+
+	// Window, if not nil, aligns the circles to its boundaries, i.e a `Calendar`,
+	// the "Per" is not used then. See `NewWindow`.
+	Window Window
+
+	// WarmUp, if not zero, is the time that a cold limiter needs to allow its full "Max",
+	// like a slow start. A new limiter, or one that is idle for a while, is cold and it allows
+	// the "Max" divided by the ColdFactor per circle, the limit ramps up to the "Max" while
+	// the limiter is in use and it cools down again while it's idle, at the same pace.
+	// It's useful for remote services which can't handle a full burst after an idle period.
+	WarmUp time.Duration
+	// ColdFactor is the factor that the "Max" is divided by when the limiter is cold,
+	// defaults to the `DefaultColdFactor`. See `WarmUp`.
+	ColdFactor float64
+
+	// PauseMode is how the new operations are treated while the limiter is paused,
+	// they wait by default. See `Pause`.
+	PauseMode PauseMode
+
 	// starting from zero,
 	// it's fair because when starting is not a complete circle:)
 	circle uint64
@@ -22,6 +51,32 @@ type C struct { // C is actually a kinda of rate limiter :)
 	mu        sync.RWMutex
 	length    uint32 // starting from zero but it will be 1 at first Acquire.
 	lastAdded int64  // starting from zero time.
+
+	// cumulative counters, see `Stats`.
+	acquired uint64
+	rejected uint64
+	waited   uint64
+	waiting  int64
+	dryRuns  uint64
+
+	waitsOnce sync.Once
+	waits     atomic.Value // *histogram.
+
+	observers atomic.Value // []Observer, see `Observe`.
+	closed    uint32
+	lockID    uint64 // see `Group`.
+
+	paused   bool                    // protected by the "mu".
+	held     []*waiter               // the `Acquire` calls while paused, protected by the "mu".
+	timers   map[*waiter]*time.Timer // the scheduled `Acquire` calls, protected by the "mu".
+	draining bool                    // protected by the "mu".
+	dryRun   bool                    // protected by the "mu".
+	debt     uint32                  // see `Charge#Settle`, protected by the "mu".
+
+	// see `WarmUp`, protected by the "mu".
+	warmth    float64
+	warmedAt  int64
+	lastAsked int64
 }
 
 // New initializes and returns a new C chronos.
@@ -53,14 +108,18 @@ func (c *C) increment(delta uint32) uint32 {
 	return atomic.AddUint32(&c.length, delta)
 }
 
-func (c *C) resetLength() {
-	atomic.StoreUint32(&c.length, 0)
+func (c *C) setLength(length uint32) {
+	atomic.StoreUint32(&c.length, length)
 }
 
 func (c *C) drawCircle() {
 	atomic.AddUint64(&c.circle, 1)
 }
 
+func (c *C) setCircle(circle uint64) {
+	atomic.StoreUint64(&c.circle, circle)
+}
+
 // Circle returns the current "circle".
 // A circle is changed when a new group of operations
 // are called or when the sched duration passed and `Acquire` is called.
@@ -70,46 +129,611 @@ func (c *C) Circle() uint64 {
 
 var emptyStruct = struct{}{}
 
-func (c *C) tryAcquire(ch chan struct{}, inc bool) {
-	current := time.Now().UnixNano()
+func (c *C) newWaitHistogram() *histogram {
+	buckets := c.WaitBuckets
+	if len(buckets) == 0 {
+		buckets = DefaultWaitBuckets
+	}
+	return newHistogram(buckets)
+}
+
+func (c *C) waitHistogram() *histogram {
+	c.waitsOnce.Do(func() {
+		c.waits.Store(c.newWaitHistogram())
+	})
+	return c.waits.Load().(*histogram)
+}
+
+// ResetWaits resets the wait durations histogram,
+// a change of the `WaitBuckets` takes effect after that.
+// It returns the histogram before the reset.
+func (c *C) ResetWaits() Histogram {
+	old := c.waitHistogram()
+	c.waits.Store(c.newWaitHistogram())
+	return old.snapshot()
+}
+
+// Limiter is the interface which the `C` implements,
+// it's useful for limiters that should be used as a `C`, i.e a remote one.
+type Limiter interface {
+	// Acquire blocks until the operation is allowed.
+	Acquire() <-chan struct{}
+	// Allow reports whether the operation is allowed now,
+	// it never blocks and the operation is counted only when allowed.
+	Allow() bool
+	// Reserve counts the operation and returns how long
+	// the caller should wait before execute it.
+	Reserve() time.Duration
+	// Stats returns a snapshot of the limiter's state.
+	Stats() Stats
+}
+
+var _ Limiter = (*C)(nil)
+
+// waiter is an `Acquire` call.
+type waiter struct {
+	ch        chan struct{}
+	start     int64
+	n         uint32 // the operations, see `WaitN`.
+	circle    uint64 // the circle that it's allowed in, protected by the limiter's "mu".
+	counted   bool   // not allowed over the limit in dry-run mode, protected by the limiter's "mu".
+	scheduled bool   // protected by the limiter's "mu".
+	done      bool   // allowed or released, protected by the limiter's "mu".
+	canceled  bool   // see `Wait`, protected by the limiter's "mu".
+}
+
+func newWaiter(n uint32) *waiter {
+	// buffered, the receiver may not be there, i.e `Reserve`.
+	return &waiter{ch: make(chan struct{}, 1), start: time.Now().UnixNano(), n: n}
+}
+
+func (c *C) grant(w *waiter, now int64) {
+	wait := time.Duration(now - w.start)
+
+	atomic.AddUint64(&c.acquired, uint64(w.n))
+	if w.scheduled {
+		atomic.AddUint64(&c.waited, uint64(w.n))
+		atomic.AddInt64(&c.waiting, -1)
+	}
+	c.waitHistogram().observe(wait)
+	c.notifyAcquire(w.n, wait)
+}
+
+// check reports whether "n" operations are allowed at "current" time, without counting them,
+// otherwise it returns the duration that should be passed before retry.
+// The "roll" reports whether a new circle should be drawn first.
+// It must be called under lock.
+func (c *C) check(current int64, n uint32) (sched int64, ok, roll bool) {
 	lastAdded := c.getLastAdded()
 
-	if lastAdded != 0 && current-lastAdded-c.Per > 0 {
-		c.drawCircle()
-		c.resetLength()
+	var end int64
+	if c.Window != nil {
+		var start int64
+		start, end = c.window(current)
+		roll = lastAdded != 0 && lastAdded < start
+	} else {
+		roll = lastAdded != 0 && current-lastAdded-c.Per > 0
+	}
+
+	length := c.getCurrentLength()
+	if roll {
+		length = c.carried()
 	}
 
 	// if the current length is smaller than the max
 	// then we don't have to check for anything else,
 	// it's available.
 	// Remember: length starts from 0 when max from 1.
-	if c.getCurrentLength() < c.Max {
-		if inc { // no need of ratio between actions, yet.
-			c.increment(1)
-		}
+	if max := c.limit(current); length < max && max-length >= n {
+		return 0, true, roll
+	}
+
+	// else schedule that.
+	if c.Window != nil {
+		return end - current, false, roll
+	}
 
+	// the new circle, which the debt already fills, starts now.
+	if roll || lastAdded == 0 {
+		return c.Per, false, roll
+	}
+
+	return c.Per - (current - lastAdded), false, roll
+}
+
+// take counts "n" operations if they are allowed at "current" time
+// otherwise it returns the duration that should be passed before retry.
+// The "circle" is not zero when a new circle is drawn.
+// In dry-run mode the operations are always allowed, a positive "sched"
+// with an "ok" means that they exceeded the limit, they are not counted then.
+// It must be called under lock.
+func (c *C) take(current int64, n uint32) (sched int64, ok bool, circle uint64) {
+	c.ask(current)
+	sched, ok, roll := c.check(current, n)
+	if roll {
+		circle = c.roll(current)
+	}
+
+	if ok {
+		c.increment(n)
 		c.setLastAdded(current)
-		ch <- emptyStruct
+		return 0, true, circle
+	}
+
+	if c.dryRun {
+		atomic.AddUint64(&c.dryRuns, uint64(n))
+		return sched, true, circle
+	}
+
+	return sched, false, circle
+}
+
+func (c *C) tryAcquire(w *waiter) {
+	now := time.Now().UnixNano()
+
+	c.mu.Lock()
+	if w.canceled {
+		c.mu.Unlock()
 		return
 	}
 
-	// else schedule that.
-	sched := current - lastAdded
-	if sched <= c.Per {
-		sched = c.Per - sched
+	if c.IsClosed() {
+		w.done = true
+		c.mu.Unlock()
+
+		if w.scheduled {
+			atomic.AddInt64(&c.waiting, -1)
+		}
+		close(w.ch)
+		return
+	}
+
+	if !w.scheduled {
+		if err := c.admit(); err != nil {
+			w.done = true
+			c.mu.Unlock()
+
+			c.reject(w.n)
+			close(w.ch)
+			return
+		}
+	}
+
+	if c.paused {
+		c.hold(w)
+		c.mu.Unlock()
+		return
+	}
+
+	sched, ok, circle := c.take(now, w.n)
+	if ok {
+		w.done = true
+		w.circle = c.Circle()
+		w.counted = sched == 0
+	} else {
+		c.enqueue(w)
+	}
+	c.mu.Unlock()
+
+	if circle != 0 {
+		c.notifyCircle(circle)
+	}
+
+	if ok {
+		if sched > 0 {
+			c.notifyDryRun(w.n, time.Duration(sched))
+		}
+		c.grant(w, now)
+		w.ch <- emptyStruct
+		return
+	}
+
+	c.schedule(w, sched)
+}
+
+// enqueue counts the "w" as waiting, it must be called under lock.
+func (c *C) enqueue(w *waiter) {
+	if !w.scheduled {
+		w.scheduled = true
+		atomic.AddInt64(&c.waiting, 1)
 	}
+}
+
+// queueFull reports whether the `QueueSize` is reached, it must be called under lock.
+func (c *C) queueFull() bool {
+	return c.QueueSize > 0 && atomic.LoadInt64(&c.waiting) >= int64(c.QueueSize)
+}
 
-	time.AfterFunc(time.Duration(sched), func() {
+// schedule retries the "w" after "sched", it must be enqueued.
+func (c *C) schedule(w *waiter, sched int64) {
+	c.notifyWait(w.n, time.Duration(sched))
+
+	c.mu.Lock()
+	if c.timers == nil {
+		c.timers = make(map[*waiter]*time.Timer)
+	}
+	c.timers[w] = time.AfterFunc(time.Duration(sched), func() {
 		c.mu.Lock()
-		c.tryAcquire(ch, true)
+		delete(c.timers, w)
 		c.mu.Unlock()
+
+		c.tryAcquire(w)
 	})
+	c.mu.Unlock()
+}
+
+// wake retries the scheduled operations now, in the order that they were asked,
+// i.e when the limit is changed or the limiter is closed.
+func (c *C) wake() {
+	c.mu.Lock()
+	woken := make([]*waiter, 0, len(c.timers))
+	for w, t := range c.timers {
+		if t.Stop() {
+			woken = append(woken, w)
+			delete(c.timers, w)
+		}
+	}
+	c.mu.Unlock()
+
+	sort.Slice(woken, func(i, j int) bool { return woken[i].start < woken[j].start })
+	c.release(woken)
 }
 
 // Acquire is the only one function of the chronos core.
 // It will block if the already called times are > than the given "max" operations.
+//
+// After `Close` the returned channel is closed without a value,
+// use the `_, ok := <-c.Acquire()` form to check that.
 func (c *C) Acquire() <-chan struct{} {
-	ch := make(chan struct{}, 0)
-	go c.tryAcquire(ch, true)
-	return ch
+	w := newWaiter(1)
+	go c.tryAcquire(w)
+	return w.ch
+}
+
+// Allow reports whether an operation is allowed now,
+// if it's allowed then it's counted as an `Acquire` would do.
+// It never blocks.
+func (c *C) Allow() bool {
+	return c.AllowN(1)
+}
+
+// AllowN is like `Allow` but for "n" operations at once,
+// they are allowed only if all of them fit in the current circle.
+func (c *C) AllowN(n uint32) bool {
+	if c.IsClosed() {
+		return false
+	}
+
+	ok, _, _, _, _ := c.allowN(n)
+	return ok
+}
+
+// allowN counts "n" operations if they are allowed now, "at" the returned circle,
+// otherwise it returns the limit that they exceeded
+// or the reason that they are not admitted, see `Pause` and `Drain`.
+// The "counted" is false for the operations which are allowed over the limit in dry-run mode.
+func (c *C) allowN(n uint32) (ok bool, at uint64, counted bool, limited LimitError, err error) {
+	var (
+		sched  int64
+		circle uint64
+	)
+
+	now := time.Now().UnixNano()
+
+	c.mu.Lock()
+	switch {
+	case c.draining:
+		err = ErrDraining
+	case c.paused:
+		// it can't wait, whatever the `PauseMode` is.
+		err = ErrPaused
+	default:
+		sched, ok, circle = c.take(now, n)
+		at = c.Circle()
+		counted = ok && sched == 0
+	}
+
+	if !ok && err == nil {
+		limited = LimitError{
+			Limit:      c.limit(now),
+			Per:        c.period(now),
+			RetryAfter: time.Duration(sched),
+		}
+		if length := c.getCurrentLength(); length < limited.Limit {
+			limited.Remaining = limited.Limit - length
+		}
+	}
+	c.mu.Unlock()
+
+	if circle != 0 {
+		c.notifyCircle(circle)
+	}
+
+	if ok {
+		if sched > 0 {
+			c.notifyDryRun(n, time.Duration(sched))
+		}
+		atomic.AddUint64(&c.acquired, uint64(n))
+		c.notifyAcquire(n, 0)
+	} else {
+		c.reject(n)
+	}
+	return
+}
+
+func (c *C) reject(n uint32) {
+	atomic.AddUint64(&c.rejected, uint64(n))
+	c.notifyReject(n)
+}
+
+// Take is like `Allow` but it returns an error instead,
+// see `TakeN`.
+func (c *C) Take() error {
+	return c.TakeN(1)
+}
+
+// TakeN is like `AllowN` but it reports why the "n" operations are not allowed:
+// `ErrClosed` after `Close`, `ErrPaused` or `ErrDraining`, see `Pause` and `Drain`,
+// `ErrCostExceedsLimit` if they would never fit in a circle
+// or a `*LimitError` which tells when to retry.
+func (c *C) TakeN(n uint32) error {
+	_, _, err := c.takeN(n)
+	return err
+}
+
+// takeN is the `TakeN`, it returns the circle that the operations are allowed in
+// and whether they are counted, see `allowN`.
+func (c *C) takeN(n uint32) (uint64, bool, error) {
+	if c.IsClosed() {
+		return 0, false, ErrClosed
+	}
+
+	c.mu.RLock()
+	max, dryRun := c.Max, c.dryRun
+	c.mu.RUnlock()
+
+	if n > max && !dryRun {
+		c.reject(n)
+		return 0, false, ErrCostExceedsLimit
+	}
+
+	ok, at, counted, limited, err := c.allowN(n)
+	if ok {
+		return at, counted, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return 0, false, &limited
+}
+
+// Wait blocks until an operation is allowed, like `Acquire`,
+// or the "ctx" is done. It returns the "ctx" error, `ErrClosed`
+// if the limiter is closed before the operation is allowed,
+// `ErrQueueFull` if the operation should wait but the `QueueSize` is reached
+// or `ErrPaused` and `ErrDraining`, see `Pause` and `Drain`.
+//
+// An operation which is canceled by the "ctx" is not counted.
+func (c *C) Wait(ctx context.Context) error {
+	return c.WaitN(ctx, 1)
+}
+
+// WaitN is like `Wait` but for "n" operations at once,
+// they are allowed when all of them fit in a circle.
+// It returns `ErrCostExceedsLimit` if they would never fit in a circle, see `TakeN`.
+func (c *C) WaitN(ctx context.Context, n uint32) error {
+	_, _, err := c.waitN(ctx, n)
+	return err
+}
+
+// waitN is the `WaitN`, it returns the circle that the operations are allowed in
+// and whether they are counted, see `allowN`.
+func (c *C) waitN(ctx context.Context, n uint32) (uint64, bool, error) {
+	if c.IsClosed() {
+		return 0, false, ErrClosed
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+
+	w := newWaiter(n)
+
+	var (
+		sched  int64
+		ok     bool
+		circle uint64
+	)
+
+	c.mu.Lock()
+	if err := c.admit(); err != nil {
+		c.mu.Unlock()
+		c.reject(n)
+		return 0, false, err
+	}
+
+	if n > c.Max && !c.dryRun {
+		c.mu.Unlock()
+		c.reject(n)
+		return 0, false, ErrCostExceedsLimit
+	}
+
+	held := c.paused
+	if !held {
+		sched, ok, circle = c.take(w.start, n)
+	}
+
+	if !ok {
+		if c.queueFull() {
+			c.mu.Unlock()
+			if circle != 0 {
+				c.notifyCircle(circle)
+			}
+			c.reject(n)
+			return 0, false, ErrQueueFull
+		}
+
+		if held {
+			c.hold(w)
+		} else {
+			c.enqueue(w)
+		}
+	} else {
+		w.done = true
+		w.circle = c.Circle()
+		w.counted = sched == 0
+	}
+	c.mu.Unlock()
+
+	if circle != 0 {
+		c.notifyCircle(circle)
+	}
+
+	if ok {
+		if sched > 0 {
+			c.notifyDryRun(n, time.Duration(sched))
+		}
+		c.grant(w, w.start)
+		return w.circle, w.counted, nil
+	}
+
+	if !held {
+		c.schedule(w, sched)
+	}
+
+	select {
+	case _, ok = <-w.ch:
+	case <-ctx.Done():
+		c.mu.Lock()
+		if !w.done {
+			w.canceled = true
+			c.mu.Unlock()
+			atomic.AddInt64(&c.waiting, -1)
+			return 0, false, ctx.Err()
+		}
+		c.mu.Unlock()
+		// allowed or released in the meantime.
+		_, ok = <-w.ch
+	}
+
+	if !ok {
+		return 0, false, ErrClosed
+	}
+
+	c.mu.RLock()
+	circle, counted := w.circle, w.counted
+	c.mu.RUnlock()
+	return circle, counted, nil
+}
+
+// Never is the duration that `Reserve` returns when
+// the operation will never be allowed, i.e the limiter is closed.
+const Never = time.Duration(1<<63 - 1)
+
+// Reserve counts an operation and returns the duration that the caller
+// should wait before execute it, zero means that it can be executed immediately.
+//
+// If the operation is not allowed now, it's scheduled like an `Acquire`
+// and the returned duration is the estimated time of its schedule.
+// After `Close` it returns `Never`, while paused the operation is scheduled
+// for after the `Resume` and it returns `Never` as well because the time is unknown.
+// A not admitted operation, see `Pause` and `Drain`, is not counted and it returns `Never`.
+func (c *C) Reserve() time.Duration {
+	if c.IsClosed() {
+		return Never
+	}
+
+	w := newWaiter(1)
+
+	c.mu.Lock()
+	if err := c.admit(); err != nil {
+		c.mu.Unlock()
+		c.reject(1)
+		return Never
+	}
+
+	if c.paused {
+		c.hold(w)
+		c.mu.Unlock()
+		return Never
+	}
+	sched, ok, circle := c.take(w.start, 1)
+	if !ok {
+		c.enqueue(w)
+	}
+	c.mu.Unlock()
+
+	if circle != 0 {
+		c.notifyCircle(circle)
+	}
+
+	if ok {
+		if sched > 0 {
+			c.notifyDryRun(1, time.Duration(sched))
+		}
+		c.grant(w, w.start)
+		return 0
+	}
+
+	c.schedule(w, sched)
+	return time.Duration(sched)
+}
+
+// SetLimit changes the "max" operations "per" time duration of the limiter,
+// the "per" is not used if the limiter has a `Window`,
+// it's safe to be called while the limiter is in use.
+// The current circle is kept and the scheduled operations retry immediately with the new limit.
+func (c *C) SetLimit(max uint32, per time.Duration) {
+	c.mu.Lock()
+	c.Max = max
+	c.Per = int64(per)
+	c.mu.Unlock()
+
+	c.wake()
+}
+
+// SetDryRun enables or disables the dry-run mode.
+// In dry-run mode the limit is not enforced, all operations are allowed immediately,
+// but the ones that exceed the limit are reported to the `DryRunObserver`s
+// and counted in the `Stats#DryRuns`, they are not counted in the circle's length.
+// It's useful to see what a new limit would do before enforce it.
+//
+// A paused limiter is not affected, see `Pause`.
+func (c *C) SetDryRun(dryRun bool) {
+	c.mu.Lock()
+	c.dryRun = dryRun
+	c.mu.Unlock()
+}
+
+// IsDryRun reports whether the limiter is in dry-run mode, see `SetDryRun`.
+func (c *C) IsDryRun() bool {
+	c.mu.RLock()
+	dryRun := c.dryRun
+	c.mu.RUnlock()
+	return dryRun
+}
+
+// Close closes the limiter, it doesn't allow any operation after that.
+// The next `Acquire` calls and the scheduled ones are released
+// with a closed channel, the `Allow` reports false.
+//
+// It's safe to be called more than once, the `Observer#OnClose` is notified once.
+func (c *C) Close() error {
+	if atomic.CompareAndSwapUint32(&c.closed, 0, 1) {
+		c.mu.Lock()
+		held := c.held
+		c.held = nil
+		c.mu.Unlock()
+
+		c.release(held) // with a closed channel.
+		c.wake()
+
+		c.notifyClose()
+	}
+	return nil
+}
+
+// IsClosed reports whether the limiter is closed.
+func (c *C) IsClosed() bool {
+	return atomic.LoadUint32(&c.closed) == 1
 }
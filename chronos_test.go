@@ -146,3 +146,53 @@ func testChronosSchedDuration(t *testing.T, c *C, i uint32) {
 		}
 	}
 }
+
+func TestAllowReserve(t *testing.T) {
+	per := 500 * time.Millisecond
+	c := New(2, per)
+
+	if !c.Allow() {
+		t.Fatalf("expected first operation to be allowed")
+	}
+
+	if wait := c.Reserve(); wait != 0 {
+		t.Fatalf("expected second operation to be reserved without wait but got %s", wait)
+	}
+
+	if c.Allow() {
+		t.Fatalf("expected third operation to not be allowed")
+	}
+
+	if st := c.Stats(); st.Length != 2 || st.Remaining != 0 || st.ResetAfter <= 0 {
+		t.Fatalf("unexpected stats: %#+v", st)
+	}
+
+	if wait := c.Reserve(); wait <= 0 || wait > per {
+		t.Fatalf("expected reserve to wait for the current circle but got %s", wait)
+	}
+
+	time.Sleep(per + per/2)
+	if st := c.Stats(); st.Circle != 1 || st.Length != 1 {
+		t.Fatalf("expected the reserved operation to be counted on the next circle but got: %#+v", st)
+	}
+
+	if st := c.Stats(); st.Acquired != 3 || st.Rejected != 1 || st.Waited != 1 || st.Waiting != 0 || st.Wait.Count != 2 {
+		t.Fatalf("unexpected counters: %#+v", st)
+	}
+}
+
+func TestSetLimit(t *testing.T) {
+	c := New(1, time.Minute)
+	if !c.Allow() || c.Allow() {
+		t.Fatalf("expected only the first operation to be allowed")
+	}
+
+	c.SetLimit(3, time.Minute)
+	if !c.Allow() || !c.Allow() || c.Allow() {
+		t.Fatalf("expected two more operations to be allowed on the current circle")
+	}
+
+	if st := c.Stats(); st.Max != 3 || st.Length != 3 {
+		t.Fatalf("unexpected stats: %#+v", st)
+	}
+}
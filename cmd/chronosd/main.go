@@ -0,0 +1,89 @@
+// Command chronosd is a rate limit sidecar server,
+// it hosts the named limiters of a JSON configuration file
+// and exposes them over HTTP/JSON and, optionally, the Redis protocol,
+// see the ext/sidecar package.
+//
+//	$ chronosd -config chronosd.json
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kataras/chronos/ext/sidecar"
+)
+
+func main() {
+	var (
+		configFile      = flag.String("config", "chronosd.json", "the JSON configuration file")
+		addr            = flag.String("addr", "", "the address to listen on, overrides the configuration's one")
+		respAddr        = flag.String("resp-addr", "", "the address of the Redis protocol frontend, overrides the configuration's one")
+		shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "the time to wait for the pending requests on shutdown")
+	)
+	flag.Parse()
+
+	cfg, err := sidecar.LoadConfig(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *addr != "" {
+		cfg.Addr = *addr
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+	if *respAddr != "" {
+		cfg.RESPAddr = *respAddr
+	}
+
+	s, err := sidecar.NewServer(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := &http.Server{Addr: cfg.Addr, Handler: s}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+
+		log.Printf("shutting down...")
+		s.Close() // the RESP frontend.
+		// release the pending acquire requests with an error,
+		// instead of dropping their connections after the timeout.
+		s.CloseLimiters()
+
+		ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}()
+
+	if cfg.RESPAddr != "" {
+		go func() {
+			log.Printf("serving the Redis protocol on %s", cfg.RESPAddr)
+			if err := s.ListenAndServeRESP(cfg.RESPAddr); err != sidecar.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	log.Printf("serving %d limiters on %s", len(s.Names()), cfg.Addr)
+	if err = srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+
+	<-done
+}
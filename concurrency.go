@@ -0,0 +1,203 @@
+package chronos
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Concurrency limits the operations that run at the same time,
+// the in-flight ones, unlike the `C` which limits the operations that start per time.
+// An operation holds a slot from its `Acquire` until its release function is called.
+//
+// Use the `NewConcurrency` function or the `Combined` one
+// to limit both the rate and the in-flight operations.
+type Concurrency struct {
+	mu       sync.Mutex
+	max      uint32
+	inFlight uint32
+	queue    []*slotWaiter // FIFO.
+
+	acquired uint64
+	rejected uint64
+}
+
+type slotWaiter struct {
+	ch      chan struct{}
+	granted bool // protected by the Concurrency's "mu".
+}
+
+// NewConcurrency returns a new limiter of "max" in-flight operations.
+func NewConcurrency(max uint32) *Concurrency {
+	return &Concurrency{max: max}
+}
+
+// releaser returns a release function of a slot which can be called more than once.
+func (l *Concurrency) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(l.release)
+	}
+}
+
+func (l *Concurrency) release() {
+	l.mu.Lock()
+	l.inFlight--
+	l.grant()
+	l.mu.Unlock()
+}
+
+// grant passes the free slots to the waiting operations, it must be called under lock.
+func (l *Concurrency) grant() {
+	for len(l.queue) > 0 && l.inFlight < l.max {
+		w := l.queue[0]
+		l.queue[0] = nil
+		l.queue = l.queue[1:]
+
+		l.inFlight++
+		l.acquired++
+		w.granted = true
+		close(w.ch)
+	}
+}
+
+// TryAcquire acquires a slot if there is a free one now, it never blocks.
+// The returned function releases the slot, it's nil when not allowed.
+func (l *Concurrency) TryAcquire() (release func(), ok bool) {
+	l.mu.Lock()
+	if l.inFlight >= l.max || len(l.queue) > 0 {
+		l.rejected++
+		l.mu.Unlock()
+		return nil, false
+	}
+
+	l.inFlight++
+	l.acquired++
+	l.mu.Unlock()
+	return l.releaser(), true
+}
+
+// Acquire blocks until a slot is free or the "ctx" is done,
+// the slots are given in the order that they were asked.
+// The returned function releases the slot, it should be called
+// when the operation is completed.
+func (l *Concurrency) Acquire(ctx context.Context) (release func(), err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	if l.inFlight < l.max && len(l.queue) == 0 {
+		l.inFlight++
+		l.acquired++
+		l.mu.Unlock()
+		return l.releaser(), nil
+	}
+
+	w := &slotWaiter{ch: make(chan struct{})}
+	l.queue = append(l.queue, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ch:
+		return l.releaser(), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		if w.granted {
+			// granted in the meantime.
+			l.mu.Unlock()
+			return l.releaser(), nil
+		}
+
+		for i, queued := range l.queue {
+			if queued == w {
+				l.queue = append(l.queue[:i], l.queue[i+1:]...)
+				break
+			}
+		}
+		l.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// SetLimit changes the "max" in-flight operations,
+// it's safe to be called while the limiter is in use.
+// A smaller limit doesn't affect the operations which are already in-flight.
+func (l *Concurrency) SetLimit(max uint32) {
+	l.mu.Lock()
+	l.max = max
+	l.grant()
+	l.mu.Unlock()
+}
+
+// ConcurrencyStats is a snapshot of a concurrency limiter's state.
+type ConcurrencyStats struct {
+	Max      uint32 `json:"max"`
+	InFlight uint32 `json:"inFlight"`
+	Waiting  int    `json:"waiting"`
+
+	Acquired uint64 `json:"acquired"` // cumulative.
+	Rejected uint64 `json:"rejected"` // operations not allowed by `TryAcquire`, cumulative.
+}
+
+// Stats returns a snapshot of the limiter's state.
+func (l *Concurrency) Stats() ConcurrencyStats {
+	l.mu.Lock()
+	st := ConcurrencyStats{
+		Max:      l.max,
+		InFlight: l.inFlight,
+		Waiting:  len(l.queue),
+		Acquired: l.acquired,
+		Rejected: l.rejected,
+	}
+	l.mu.Unlock()
+	return st
+}
+
+// Combined limits both the rate and the in-flight operations,
+// i.e "max 10 concurrent requests and 100 per minute".
+type Combined struct {
+	Rate     *C
+	InFlight *Concurrency
+}
+
+// NewCombined returns a new limiter of "max" operations "per" time duration
+// and "maxInFlight" operations at the same time.
+func NewCombined(max uint32, per time.Duration, maxInFlight uint32) *Combined {
+	return &Combined{
+		Rate:     New(max, per),
+		InFlight: NewConcurrency(maxInFlight),
+	}
+}
+
+// Acquire blocks until a slot is free and the rate allows the operation,
+// or the "ctx" is done, see `Concurrency#Acquire` and `C#Wait`.
+// The slot is acquired first, so the rate is not spent while waiting for a slot.
+// The returned function releases the slot.
+func (l *Combined) Acquire(ctx context.Context) (release func(), err error) {
+	if release, err = l.InFlight.Acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	if err = l.Rate.Wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// TryAcquire is like `Acquire` but it never blocks,
+// the returned function is nil when not allowed.
+func (l *Combined) TryAcquire() (release func(), ok bool) {
+	if release, ok = l.InFlight.TryAcquire(); !ok {
+		return nil, false
+	}
+
+	if !l.Rate.Allow() {
+		release()
+		return nil, false
+	}
+
+	return release, true
+}
@@ -0,0 +1,95 @@
+package chronos
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConcurrency(t *testing.T) {
+	l := NewConcurrency(2)
+
+	release1, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release2, ok := l.TryAcquire()
+	if !ok {
+		t.Fatalf("expected the second slot to be acquired")
+	}
+	if _, ok = l.TryAcquire(); ok {
+		t.Fatalf("expected no free slot")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = l.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context deadline but got %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		release, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- release
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("expected acquire to wait for a free slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release1()
+	release1() // no-op.
+
+	var release3 func()
+	select {
+	case release3 = <-acquired:
+	case <-time.After(time.Second):
+		t.Fatalf("expected acquire to be allowed after a release")
+	}
+
+	if st := l.Stats(); st.InFlight != 2 || st.Waiting != 0 || st.Acquired != 3 || st.Rejected != 1 {
+		t.Fatalf("unexpected stats: %#+v", st)
+	}
+
+	release2()
+	release3()
+	if st := l.Stats(); st.InFlight != 0 {
+		t.Fatalf("expected no in-flight operation but got %d", st.InFlight)
+	}
+}
+
+func TestCombined(t *testing.T) {
+	l := NewCombined(2, time.Hour, 1)
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := l.TryAcquire(); ok {
+		t.Fatalf("expected no free slot")
+	}
+	if st := l.Rate.Stats(); st.Length != 1 {
+		t.Fatalf("expected the rate to not be spent without a slot but got length %d", st.Length)
+	}
+
+	release()
+	if release, ok := l.TryAcquire(); !ok {
+		t.Fatalf("expected operation to be allowed after a release")
+	} else {
+		release()
+	}
+
+	// the rate is spent, the slot is released.
+	if _, ok := l.TryAcquire(); ok {
+		t.Fatalf("expected operation to not be allowed by the rate")
+	}
+	if st := l.InFlight.Stats(); st.InFlight != 0 {
+		t.Fatalf("expected the slot to be released but got %d in-flight", st.InFlight)
+	}
+}
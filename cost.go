@@ -0,0 +1,139 @@
+package chronos
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Charge is the estimated cost of operations which are allowed by `WaitCost` or `TakeCost`,
+// i.e the points of a GraphQL query. The actual cost, when it's known,
+// i.e from the response, should be given to `Settle`.
+type Charge struct {
+	c        *C
+	circle   uint64
+	estimate uint32
+	counted  bool   // false when it's allowed over the limit in dry-run mode.
+	settled  uint32 // atomic.
+}
+
+// WaitCost is like `WaitN` for an "estimate" cost,
+// the returned `Charge` should be settled with the actual cost.
+func (c *C) WaitCost(ctx context.Context, estimate uint32) (*Charge, error) {
+	circle, counted, err := c.waitN(ctx, estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Charge{c: c, circle: circle, estimate: estimate, counted: counted}, nil
+}
+
+// TakeCost is like `TakeN` for an "estimate" cost,
+// the returned `Charge` should be settled with the actual cost.
+func (c *C) TakeCost(estimate uint32) (*Charge, error) {
+	circle, counted, err := c.takeN(estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Charge{c: c, circle: circle, estimate: estimate, counted: counted}, nil
+}
+
+// Estimate returns the estimated cost that the charge counted.
+func (ch *Charge) Estimate() uint32 {
+	return ch.estimate
+}
+
+// Settle settles the charge with the "actual" cost, only the first call counts.
+//
+// A smaller cost is refunded to the current circle, so the waiting operations
+// retry immediately, but only if the circle is the one that the charge counted in,
+// otherwise its capacity is already renewed.
+// A greater cost is debited to the current circle, the part that doesn't fit
+// is a debt which is carried over to the next circles and delays the later operations.
+// A charge which was allowed over the limit in dry-run mode was not counted,
+// so its settle is a no-op.
+func (ch *Charge) Settle(actual uint32) {
+	if !atomic.CompareAndSwapUint32(&ch.settled, 0, 1) || actual == ch.estimate || !ch.counted {
+		return
+	}
+
+	ch.c.settle(ch.circle, ch.estimate, actual)
+}
+
+// Refund refunds the charge, i.e when the operations failed before they reached the
+// remote service so they didn't count there, it's a `Settle` with zero cost.
+// It restores the capacity of the current circle only if it's the circle
+// that the charge counted in, otherwise it's a no-op.
+//
+// Use the `WaitCost` or `TakeCost` with a cost of 1 to get a refundable operation.
+func (ch *Charge) Refund() {
+	ch.Settle(0)
+}
+
+func (c *C) settle(circle uint64, charged, actual uint32) {
+	if c.IsClosed() {
+		return
+	}
+
+	now := time.Now().UnixNano()
+
+	c.mu.Lock()
+	var drawn uint64
+	if _, _, roll := c.check(now, 0); roll {
+		drawn = c.roll(now)
+	}
+
+	refunded := false
+	length := c.getCurrentLength()
+	if actual > charged {
+		var room uint32
+		if max := c.limit(now); length < max {
+			room = max - length
+		}
+
+		if debit := actual - charged; debit <= room {
+			c.increment(debit)
+		} else {
+			c.setLength(length + room)
+			c.debt += debit - room
+		}
+	} else if c.Circle() == circle {
+		refund := charged - actual
+		if refund > length {
+			refund = length
+		}
+		c.setLength(length - refund)
+		refunded = refund > 0
+	}
+	c.mu.Unlock()
+
+	if drawn != 0 {
+		c.notifyCircle(drawn)
+	}
+
+	if refunded {
+		c.wake()
+	}
+}
+
+// carried returns the debt that the next circle starts with, see `Charge#Settle`.
+// It must be called under lock.
+func (c *C) carried() uint32 {
+	if c.debt < c.Max {
+		return c.debt
+	}
+	return c.Max
+}
+
+// roll draws a new circle at "current" time, which starts with the carried debt,
+// and returns it. It must be called under lock.
+func (c *C) roll(current int64) uint64 {
+	carried := c.carried()
+	c.debt -= carried
+
+	c.drawCircle()
+	c.setLength(carried)
+	c.setLastAdded(current)
+	return c.Circle()
+}
@@ -0,0 +1,204 @@
+package chronos
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestChargeSettle(t *testing.T) {
+	c := New(10, time.Hour)
+
+	charge, err := c.TakeCost(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	charge.Settle(1)
+	charge.Settle(8) // only the first one counts.
+	if st := c.Stats(); st.Length != 1 {
+		t.Fatalf("expected the difference to be refunded but got length %d", st.Length)
+	}
+
+	if charge, err = c.TakeCost(9); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- c.Wait(context.Background())
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("expected wait to wait for the full circle")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	charge.Settle(5)
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected wait to be allowed after the refund")
+	}
+
+	if st := c.Stats(); st.Length != 7 {
+		t.Fatalf("expected length of 7 but got %d", st.Length)
+	}
+
+	if _, err = c.TakeCost(11); err != ErrCostExceedsLimit {
+		t.Fatalf("expected ErrCostExceedsLimit but got %v", err)
+	}
+}
+
+func TestChargeDebt(t *testing.T) {
+	c := New(10, time.Minute)
+
+	charge, err := c.WaitCost(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	charge.Settle(30)
+	if st := c.Stats(); st.Length != 10 || st.Remaining != 0 || st.Debt != 20 {
+		t.Fatalf("unexpected stats after the debit: %#+v", st)
+	}
+
+	// the debt delays the next circles.
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	_, ok, _ := c.take(now+int64(time.Minute)+1, 1)
+	if ok || c.getCurrentLength() != 10 || c.debt != 10 {
+		t.Fatalf("expected the next circle to be paid by the debt")
+	}
+	_, ok, _ = c.take(now+2*int64(time.Minute)+2, 1)
+	if ok || c.debt != 0 {
+		t.Fatalf("expected the debt to be paid")
+	}
+	_, ok, _ = c.take(now+3*int64(time.Minute)+3, 1)
+	c.mu.Unlock()
+	if !ok {
+		t.Fatalf("expected operation to be allowed after the debt is paid")
+	}
+}
+
+func TestChargeDebtIdle(t *testing.T) {
+	per := 100 * time.Millisecond
+	c := New(2, per)
+
+	charge, err := c.TakeCost(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	charge.Settle(10)
+
+	// idle for a few circles, the debt fills the next one,
+	// which starts now, so the wait is one period, not the idle time.
+	time.Sleep(5 * per)
+	if wait := c.Reserve(); wait > per {
+		t.Fatalf("expected to wait at most %s but got %s", per, wait)
+	}
+}
+
+func TestChargeRefundDryRun(t *testing.T) {
+	c := New(1, time.Minute)
+	c.SetDryRun(true)
+
+	if _, err := c.TakeCost(1); err != nil {
+		t.Fatal(err)
+	}
+	// over the limit, it's not counted.
+	charge, err := c.TakeCost(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	charge.Refund()
+	if st := c.Stats(); st.Length != 1 {
+		t.Fatalf("expected the refund of an operation which was not counted to be a no-op but got length %d", st.Length)
+	}
+}
+
+func TestChargeRefundAfterRollover(t *testing.T) {
+	c := New(10, time.Minute)
+
+	charge, err := c.TakeCost(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	c.take(time.Now().Add(2*time.Minute).UnixNano(), 1)
+	c.mu.Unlock()
+
+	charge.Refund()
+	if length := c.getCurrentLength(); length != 1 {
+		t.Fatalf("expected the refund to be ignored after the circle rolled over but got length %d", length)
+	}
+}
+
+func TestChargeRefund(t *testing.T) {
+	c := New(1, time.Hour)
+
+	charge, err := c.TakeCost(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Allow() {
+		t.Fatalf("expected operation to not be allowed before the refund")
+	}
+
+	charge.Refund()
+	if !c.Allow() {
+		t.Fatalf("expected operation to be allowed after the refund")
+	}
+}
+
+func TestDebtState(t *testing.T) {
+	c := New(10, time.Minute)
+
+	charge, err := c.TakeCost(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	charge.Settle(25)
+
+	b, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New(10, time.Minute)
+	if err = restored.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if st := restored.Stats(); st.Length != 10 || st.Debt != 15 {
+		t.Fatalf("binary: expected the debt to be restored but got %#+v", st)
+	}
+
+	// the version 2 has no debt.
+	v2 := append([]byte{2}, b[1:stateV2BinarySize]...)
+	restored = New(10, time.Minute)
+	if err = restored.UnmarshalBinary(v2); err != nil {
+		t.Fatalf("expected the version 2 to be accepted but got %v", err)
+	}
+	if st := restored.Stats(); st.Length != 10 || st.Debt != 0 {
+		t.Fatalf("v2: unexpected stats: %#+v", st)
+	}
+
+	if b, err = json.Marshal(c); err != nil {
+		t.Fatal(err)
+	}
+	restored = New(10, time.Minute)
+	if err = json.Unmarshal(b, restored); err != nil {
+		t.Fatal(err)
+	}
+	if st := restored.Stats(); st.Debt != 15 {
+		t.Fatalf("json: expected the debt to be restored but got %#+v", st)
+	}
+}
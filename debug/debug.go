@@ -0,0 +1,496 @@
+// Package debug serves a live dashboard of the chronos limiters,
+// in the same manner as the standard net/http/pprof package.
+//
+// The package is typically only imported for the side effect of
+// registering its HTTP handler under the `Path` of the `http.DefaultServeMux`.
+//
+//	import _ "github.com/kataras/chronos/debug"
+//
+// Limiters are shown by name, opt-in:
+//
+//	c := chronos.New(150, time.Minute)
+//	debug.Register("ip-api", c)
+//	http.ListenAndServe(":8080", nil) // GET /debug/chronos/
+//
+// The limiters of the global registry, see `chronos.Register`, are shown as well,
+// their events are recorded since the first time that the dashboard is visited.
+//
+// The page shows the configuration, usage, queue depth and recent events
+// of each limiter and it's refreshed through Server-Sent Events.
+// If `EnableControl` is true, operators can pause, resume
+// and reconfigure the limiters from the page as well.
+//
+// To serve the dashboard on a different mux or path use the `Handler`.
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+// Path is the path that the dashboard is registered on the `http.DefaultServeMux`.
+const Path = "/debug/chronos/"
+
+// EnableControl enables the pause, resume and reconfigure actions of the dashboard.
+// It's false by default, the dashboard is read-only.
+// The cross-origin requests of browsers to those actions are rejected.
+var EnableControl = false
+
+// RefreshInterval is the time between two updates of the page.
+var RefreshInterval = time.Second
+
+// MaxEvents is the number of the recent events kept for each limiter.
+const MaxEvents = 20
+
+func init() {
+	http.Handle(Path, Handler())
+}
+
+// Event is a decision of a limiter.
+type Event struct {
+	Time     time.Time     `json:"time"`
+	Decision string        `json:"decision"` // acquire, wait, reject, circle, close or dryrun.
+	N        uint32        `json:"n,omitempty"`
+	Wait     time.Duration `json:"wait,omitempty"`
+	Circle   uint64        `json:"circle,omitempty"`
+}
+
+// events is the ring buffer of the recent events of a limiter,
+// it's the limiter's `chronos.Observer`.
+type events struct {
+	mu   sync.Mutex
+	ring [MaxEvents]Event
+	next int
+	size int
+}
+
+func (e *events) add(ev Event) {
+	ev.Time = time.Now()
+
+	e.mu.Lock()
+	e.ring[e.next] = ev
+	e.next = (e.next + 1) % MaxEvents
+	if e.size < MaxEvents {
+		e.size++
+	}
+	e.mu.Unlock()
+}
+
+// recent returns the events, newest first.
+func (e *events) recent() []Event {
+	e.mu.Lock()
+	list := make([]Event, e.size)
+	for i := range list {
+		list[i] = e.ring[(e.next-1-i+MaxEvents)%MaxEvents]
+	}
+	e.mu.Unlock()
+	return list
+}
+
+func (e *events) OnAcquire(c *chronos.C, n uint32, wait time.Duration) {
+	e.add(Event{Decision: "acquire", N: n, Wait: wait})
+}
+
+func (e *events) OnWait(c *chronos.C, delay time.Duration) {
+	e.add(Event{Decision: "wait", Wait: delay})
+}
+
+func (e *events) OnReject(c *chronos.C, n uint32) {
+	e.add(Event{Decision: "reject", N: n})
+}
+
+func (e *events) OnCircle(c *chronos.C, circle uint64) {
+	e.add(Event{Decision: "circle", Circle: circle})
+}
+
+func (e *events) OnClose(c *chronos.C) {
+	e.add(Event{Decision: "close"})
+}
+
+func (e *events) OnDryRun(c *chronos.C, n uint32, retryAfter time.Duration) {
+	e.add(Event{Decision: "dryrun", N: n, Wait: retryAfter})
+}
+
+type entry struct {
+	c      *chronos.C
+	events *events
+}
+
+var (
+	mu       sync.RWMutex
+	limiters = make(map[string]entry)
+	// observed keeps the observer of each shown limiter,
+	// a limiter registered more than once is observed once.
+	// The observer is detached when the limiter is no longer shown, see `prune`.
+	observed = make(map[*chronos.C]*events)
+)
+
+// Register shows the "c" limiter on the dashboard under the "name",
+// a limiter already registered under the same name is replaced.
+func Register(name string, c *chronos.C) {
+	mu.Lock()
+	limiters[name] = entry{c: c, events: observeLocked(c)}
+	mu.Unlock()
+}
+
+// observe returns the events of the "c", it attaches the observer on the first call.
+func observe(c *chronos.C) *events {
+	mu.Lock()
+	ev := observeLocked(c)
+	mu.Unlock()
+	return ev
+}
+
+// observeLocked is like `observe` but it must be called under the lock.
+func observeLocked(c *chronos.C) *events {
+	ev, ok := observed[c]
+	if !ok {
+		ev = new(events)
+		observed[c] = ev
+		c.Observe(ev)
+	}
+	return ev
+}
+
+// prune detaches the observer of the limiters which are no longer shown,
+// the ones unregistered from here and from the global registry.
+func prune() {
+	shown := make(map[*chronos.C]struct{})
+	for _, name := range chronos.Names() {
+		if c, ok := chronos.Get(name); ok {
+			shown[c] = struct{}{}
+		}
+	}
+
+	mu.Lock()
+	for _, e := range limiters {
+		shown[e.c] = struct{}{}
+	}
+	for c, ev := range observed {
+		if _, ok := shown[c]; !ok {
+			delete(observed, c)
+			c.Unobserve(ev)
+		}
+	}
+	mu.Unlock()
+}
+
+// Unregister removes the limiter which is registered under the "name",
+// its observer is detached if it's no longer shown.
+func Unregister(name string) {
+	mu.Lock()
+	delete(limiters, name)
+	mu.Unlock()
+
+	prune()
+}
+
+// Names returns the sorted names of the shown limiters,
+// the registered ones and the ones of the global registry.
+func Names() []string {
+	mu.RLock()
+	names := make([]string, 0, len(limiters))
+	for name := range limiters {
+		names = append(names, name)
+	}
+	mu.RUnlock()
+
+	for _, name := range chronos.Names() {
+		if _, ok := lookupLocal(name); !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func lookupLocal(name string) (entry, bool) {
+	mu.RLock()
+	e, ok := limiters[name]
+	mu.RUnlock()
+	return e, ok
+}
+
+// lookup returns the limiter registered under the "name",
+// the ones registered by `Register` take precedence over the global registry.
+func lookup(name string) (entry, bool) {
+	if e, ok := lookupLocal(name); ok {
+		return e, true
+	}
+
+	c, ok := chronos.Get(name)
+	if !ok {
+		return entry{}, false
+	}
+	return entry{c: c, events: observe(c)}, true
+}
+
+// View is the state of a limiter as it's shown on the dashboard.
+type View struct {
+	Name   string        `json:"name"`
+	Stats  chronos.Stats `json:"stats"`
+	P50    time.Duration `json:"p50"`
+	P99    time.Duration `json:"p99"`
+	Events []Event       `json:"events"`
+}
+
+// Views returns the state of all registered limiters, sorted by name.
+func Views() []View {
+	prune()
+
+	names := Names()
+	views := make([]View, 0, len(names))
+	for _, name := range names {
+		e, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		st := e.c.Stats()
+		views = append(views, View{
+			Name:   name,
+			Stats:  st,
+			P50:    st.Wait.Percentile(50),
+			P99:    st.Wait.Percentile(99),
+			Events: e.events.recent(),
+		})
+	}
+
+	return views
+}
+
+// Handler returns the dashboard as an `http.Handler`.
+// The routes are relative to the path that it's served on:
+//
+//	GET  {path}         the HTML page.
+//	GET  {path}events   the Server-Sent Events stream of the `Views`, as JSON.
+//	POST {path}control  the pause, resume and reconfigure actions, if `EnableControl`.
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/events"):
+		serveEvents(w, r)
+	case strings.HasSuffix(r.URL.Path, "/control"):
+		serveControl(w, r)
+	default:
+		serveIndex(w, r)
+	}
+}
+
+func serveIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	data := struct {
+		Views         []View
+		EnableControl bool
+	}{Views(), EnableControl}
+
+	if err := indexTmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	ticker := time.NewTicker(RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		b, err := json.Marshal(Views())
+		if err != nil {
+			return
+		}
+
+		if _, err = fmt.Fprintf(w, "event: views\ndata: %s\n\n", b); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// serveControl handles the form values: "name" of the limiter,
+// "action" which is "pause", "resume" or "reconfigure",
+// and the "max" and "per" of the reconfigure.
+func serveControl(w http.ResponseWriter, r *http.Request) {
+	if !EnableControl {
+		http.Error(w, "control is disabled", http.StatusForbidden)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin request", http.StatusForbidden)
+		return
+	}
+
+	name := r.FormValue("name")
+	e, ok := lookup(name)
+	if !ok {
+		http.Error(w, "limiter '"+name+"' not found", http.StatusNotFound)
+		return
+	}
+
+	switch action := r.FormValue("action"); action {
+	case "pause":
+		e.c.Pause()
+	case "resume":
+		e.c.Resume()
+	case "reconfigure":
+		max, err := strconv.ParseUint(r.FormValue("max"), 10, 32)
+		if err != nil || max == 0 {
+			http.Error(w, "invalid max", http.StatusBadRequest)
+			return
+		}
+
+		per, err := time.ParseDuration(r.FormValue("per"))
+		if err != nil || per <= 0 {
+			http.Error(w, "invalid per", http.StatusBadRequest)
+			return
+		}
+
+		e.c.SetLimit(uint32(max), per)
+	default:
+		http.Error(w, "unknown action '"+action+"'", http.StatusBadRequest)
+		return
+	}
+
+	// back to the page, which is the parent of the control route.
+	http.Redirect(w, r, "./", http.StatusSeeOther)
+}
+
+// sameOrigin reports whether the "r" is not a cross-origin request of a browser,
+// i.e a form of another site which is posted to the control route.
+// It trusts the Sec-Fetch-Site header and falls back to the Origin one,
+// a request without both is not sent by a browser.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+var indexTmpl = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>chronos</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.paused { color: #c00; }
+.events { font-family: monospace; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>chronos limiters</h1>
+{{if not .Views}}<p>No limiters registered, see <code>chronos.Register</code> and <code>debug.Register</code>.</p>{{end}}
+{{$control := .EnableControl}}
+{{range .Views}}
+<table id="limiter-{{.Name}}">
+<tr><th colspan="2">{{.Name}}{{if .Stats.Paused}} <span class="paused">paused</span>{{end}}{{if .Stats.Draining}} <span class="paused">draining</span>{{end}}{{if .Stats.DryRun}} <span class="paused">dry-run</span>{{end}}</th></tr>
+<tr><td>limit</td><td data-field="limit">{{.Stats.Max}} per {{.Stats.Per}}</td></tr>
+<tr><td>circle</td><td data-field="circle">{{.Stats.Circle}}</td></tr>
+<tr><td>usage</td><td data-field="usage">{{.Stats.Length}} ({{.Stats.Remaining}} remaining, resets after {{.Stats.ResetAfter}})</td></tr>
+<tr><td>queue depth</td><td data-field="waiting">{{.Stats.Waiting}}</td></tr>
+<tr><td>acquired / rejected / waited / dry-runs</td><td data-field="counters">{{.Stats.Acquired}} / {{.Stats.Rejected}} / {{.Stats.Waited}} / {{.Stats.DryRuns}}</td></tr>
+<tr><td>wait p50 / p99 / max</td><td data-field="wait">{{.P50}} / {{.P99}} / {{.Stats.Wait.Max}}</td></tr>
+<tr><td>recent events</td><td class="events" data-field="events">{{range .Events}}{{.Time.Format "15:04:05.000"}} {{.Decision}}<br>{{end}}</td></tr>
+{{if $control}}
+<tr><td>control</td><td>
+<form method="post" action="control" style="display:inline">
+<input type="hidden" name="name" value="{{.Name}}">
+{{if .Stats.Paused}}<button name="action" value="resume">resume</button>{{else}}<button name="action" value="pause">pause</button>{{end}}
+</form>
+<form method="post" action="control" style="display:inline">
+<input type="hidden" name="name" value="{{.Name}}">
+<input type="hidden" name="action" value="reconfigure">
+max <input name="max" size="6" value="{{.Stats.Max}}">
+per <input name="per" size="6" value="{{.Stats.Per}}">
+<button>reconfigure</button>
+</form>
+</td></tr>
+{{end}}
+</table>
+{{end}}
+<script>
+(function() {
+	if (!window.EventSource) { return; }
+	function ms(ns) { return (ns / 1e6).toFixed(3) + "ms"; }
+	var source = new EventSource("events");
+	source.addEventListener("views", function(e) {
+		JSON.parse(e.data).forEach(function(v) {
+			var table = document.getElementById("limiter-" + v.name);
+			if (!table) { return; }
+			var st = v.stats;
+			function set(field, text) {
+				var td = table.querySelector("[data-field=" + field + "]");
+				if (td) { td.textContent = text; }
+			}
+			set("limit", st.max + " per " + ms(st.per));
+			set("circle", st.circle);
+			set("usage", st.length + " (" + st.remaining + " remaining, resets after " + ms(st.resetAfter) + ")");
+			set("waiting", st.waiting);
+			set("counters", st.acquired + " / " + st.rejected + " / " + st.waited + " / " + st.dryRuns);
+			set("wait", ms(v.p50) + " / " + ms(v.p99) + " / " + ms(st.wait.max));
+			var events = table.querySelector("[data-field=events]");
+			if (events) {
+				events.textContent = "";
+				(v.events || []).forEach(function(ev) {
+					events.appendChild(document.createTextNode(ev.time.substr(11, 12) + " " + ev.decision));
+					events.appendChild(document.createElement("br"));
+				});
+			}
+		});
+	});
+})();
+</script>
+</body>
+</html>
+`))
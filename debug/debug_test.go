@@ -0,0 +1,200 @@
+package debug
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+func TestIndex(t *testing.T) {
+	c := chronos.New(2, time.Minute)
+	Register("test-index", c)
+	defer Unregister("test-index")
+
+	c.Allow()
+	c.Allow()
+	c.Allow()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+
+	body := rec.Body.String()
+	for _, expected := range []string{"test-index", "2 per 1m0s", "2 / 1 / 0", "reject"} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("expected the page to contain %q but got:\n%s", expected, body)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c := chronos.New(1, time.Minute)
+	Register("test-events", c)
+	defer Unregister("test-events")
+	c.Allow()
+	c.Allow()
+
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+Path+"events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if expected, got := "text/event-stream", resp.Header.Get("Content-Type"); expected != got {
+		t.Fatalf("expected content type %q but got %q", expected, got)
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		data := strings.TrimPrefix(sc.Text(), "data: ")
+		if data == sc.Text() {
+			continue
+		}
+
+		var views []View
+		if err = json.Unmarshal([]byte(data), &views); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, v := range views {
+			if v.Name != "test-events" {
+				continue
+			}
+			if v.Stats.Acquired != 1 || v.Stats.Rejected != 1 || len(v.Events) != 2 || v.Events[0].Decision != "reject" {
+				t.Fatalf("unexpected view: %#+v", v)
+			}
+			return
+		}
+		t.Fatalf("limiter not found on: %s", data)
+	}
+	t.Fatalf("no event received: %v", sc.Err())
+}
+
+func TestControl(t *testing.T) {
+	c := chronos.New(1, time.Minute)
+	Register("test-control", c)
+	defer Unregister("test-control")
+
+	post := func(values url.Values) int {
+		req := httptest.NewRequest(http.MethodPost, Path+"control", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := post(url.Values{"name": {"test-control"}, "action": {"pause"}}); code != http.StatusForbidden {
+		t.Fatalf("expected control to be disabled by default but got status %d", code)
+	}
+
+	EnableControl = true
+	defer func() { EnableControl = false }()
+
+	if code := post(url.Values{"name": {"test-control"}, "action": {"pause"}}); code != http.StatusSeeOther || !c.IsPaused() {
+		t.Fatalf("expected limiter to be paused, status %d", code)
+	}
+
+	if code := post(url.Values{"name": {"test-control"}, "action": {"resume"}}); code != http.StatusSeeOther || c.IsPaused() {
+		t.Fatalf("expected limiter to be resumed, status %d", code)
+	}
+
+	if code := post(url.Values{"name": {"test-control"}, "action": {"reconfigure"}, "max": {"5"}, "per": {"1s"}}); code != http.StatusSeeOther {
+		t.Fatalf("expected reconfigure to succeed but got status %d", code)
+	}
+	if st := c.Stats(); st.Max != 5 || st.Per != time.Second {
+		t.Fatalf("unexpected stats after reconfigure: %#+v", st)
+	}
+
+	if code := post(url.Values{"name": {"test-control"}, "action": {"reconfigure"}, "max": {"0"}, "per": {"1s"}}); code != http.StatusBadRequest {
+		t.Fatalf("expected invalid max to be rejected but got status %d", code)
+	}
+
+	if code := post(url.Values{"name": {"missing"}, "action": {"pause"}}); code != http.StatusNotFound {
+		t.Fatalf("expected unknown limiter to be not found but got status %d", code)
+	}
+
+	crossOrigin := []map[string]string{
+		{"Sec-Fetch-Site": "cross-site"},
+		{"Sec-Fetch-Site": "same-site"},
+		{"Origin": "http://evil.example.com"},
+	}
+	for _, headers := range crossOrigin {
+		req := httptest.NewRequest(http.MethodPost, Path+"control", strings.NewReader("name=test-control&action=pause"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || c.IsPaused() {
+			t.Fatalf("%v: expected a cross-origin request to be forbidden but got status %d", headers, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, Path+"control", strings.NewReader("name=test-control&action=pause"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "http://"+req.Host)
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther || !c.IsPaused() {
+		t.Fatalf("expected a same-origin request to pause the limiter but got status %d", rec.Code)
+	}
+}
+
+func TestUnregisterDetaches(t *testing.T) {
+	c := chronos.New(1, time.Minute)
+	Register("test-detach-1", c)
+	Register("test-detach-2", c)
+
+	Unregister("test-detach-1")
+	mu.RLock()
+	_, ok := observed[c]
+	mu.RUnlock()
+	if !ok {
+		t.Fatalf("expected the limiter to be observed while it's still shown")
+	}
+
+	Unregister("test-detach-2")
+	mu.RLock()
+	_, ok = observed[c]
+	mu.RUnlock()
+	if ok {
+		t.Fatalf("expected the observer to be detached")
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	c := chronos.New(3, time.Minute)
+	if err := chronos.Register("test-registered", c); err != nil {
+		t.Fatal(err)
+	}
+	defer chronos.Unregister("test-registered")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	if body := rec.Body.String(); !strings.Contains(body, "test-registered") || !strings.Contains(body, "3 per 1m0s") {
+		t.Fatalf("expected the registered limiter to be shown but got:\n%s", body)
+	}
+
+	// events are recorded since the first visit.
+	c.Allow()
+	for _, v := range Views() {
+		if v.Name == "test-registered" && (len(v.Events) != 1 || v.Events[0].Decision != "acquire") {
+			t.Fatalf("unexpected events: %#+v", v.Events)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package chronos
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrClosed is returned when an operation is asked from a closed limiter, see `Close`.
+	ErrClosed = errors.New("chronos: limiter is closed")
+	// ErrQueueFull is returned by `Wait` when the `C.QueueSize` operations already wait.
+	ErrQueueFull = errors.New("chronos: queue is full")
+	// ErrPaused is returned when an operation is asked from a paused limiter,
+	// by `Wait` only in the `PauseReject` mode, see `Pause`.
+	ErrPaused = errors.New("chronos: limiter is paused")
+	// ErrDraining is returned when an operation is asked from a draining limiter, see `Drain`.
+	ErrDraining = errors.New("chronos: limiter is draining")
+	// ErrCostExceedsLimit is returned by `TakeN` when the "n" operations
+	// are more than the limit, so they would never fit in a circle.
+	ErrCostExceedsLimit = errors.New("chronos: cost exceeds the limit")
+)
+
+// LimitError is returned when the operations are not allowed now,
+// it describes the limit and when the caller should retry.
+// Use `errors.As` to read it.
+type LimitError struct {
+	Limit     uint32        // the maximum operations
+	Per       time.Duration // per x time.
+	Remaining uint32        // the operations that can still be allowed in the current circle.
+	// RetryAfter is the time until the current circle is finished.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return "chronos: limit of " + strconv.FormatUint(uint64(e.Limit), 10) + " per " + e.Per.String() +
+		" exceeded, retry after " + e.RetryAfter.String()
+}
@@ -0,0 +1,81 @@
+package chronos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTakeN(t *testing.T) {
+	per := time.Minute
+	c := New(3, per)
+
+	if err := c.TakeN(4); err != ErrCostExceedsLimit {
+		t.Fatalf("expected ErrCostExceedsLimit but got %v", err)
+	}
+
+	if err := c.TakeN(2); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.TakeN(2)
+	var limited *LimitError
+	if !errors.As(err, &limited) {
+		t.Fatalf("expected a *LimitError but got %v", err)
+	}
+
+	if limited.Limit != 3 || limited.Per != per || limited.Remaining != 1 || limited.RetryAfter <= 0 || limited.RetryAfter > per {
+		t.Fatalf("unexpected limit error: %#+v", limited)
+	}
+
+	if err = c.Take(); err != nil {
+		t.Fatalf("expected the remaining operation to be allowed but got %v", err)
+	}
+
+	c.Close()
+	if err = c.Take(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed but got %v", err)
+	}
+}
+
+func TestWait(t *testing.T) {
+	c := New(1, 500*time.Millisecond)
+	c.QueueSize = 1
+
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.Wait(ctx) }()
+
+	// wait until it's queued.
+	for c.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := c.Wait(context.Background()); err != ErrQueueFull {
+		t.Fatalf("expected ErrQueueFull but got %v", err)
+	}
+
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Fatalf("expected context.Canceled but got %v", err)
+	}
+
+	if st := c.Stats(); st.Waiting != 0 || st.Acquired != 1 || st.Rejected != 1 {
+		t.Fatalf("unexpected stats: %#+v", st)
+	}
+
+	go func() { errCh <- c.Wait(context.Background()) }()
+	for c.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Close()
+	if err := <-errCh; err != ErrClosed {
+		t.Fatalf("expected ErrClosed but got %v", err)
+	}
+}
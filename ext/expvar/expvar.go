@@ -0,0 +1,78 @@
+// Package expvar publishes the state of chronos limiters under the standard `/debug/vars`,
+// using the standard expvar package. Publishing is opt-in, by name,
+// the limiters of the global registry, see `chronos.Register`, are published as well.
+//
+// Example Code:
+//
+//	c := chronos.New(150, time.Minute)
+//	expvar.Publish("ip-api", c)
+//	http.ListenAndServe(":8080", nil) // GET /debug/vars
+//
+// The limiters are published as fields of the "chronos" variable:
+//
+//	"chronos": {"ip-api": {"max": 150, "per": 60000000000, "circle": 0, ...}}
+package expvar
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+
+	"github.com/kataras/chronos"
+)
+
+// VarName is the name of the expvar variable which holds the published limiters.
+const VarName = "chronos"
+
+var (
+	mu       sync.RWMutex
+	limiters = make(map[string]*chronos.C)
+)
+
+func init() {
+	expvar.Publish(VarName, expvar.Func(func() interface{} {
+		vars := make(map[string]chronos.Stats)
+		chronos.Range(func(name string, c *chronos.C) bool {
+			vars[name] = c.Stats()
+			return true
+		})
+
+		// the published ones take precedence over the registered ones with the same name.
+		mu.RLock()
+		for name, c := range limiters {
+			// Stats reads the limiter's state under its lock or atomically,
+			// it's safe to be called while the limiter is in use.
+			vars[name] = c.Stats()
+		}
+		mu.RUnlock()
+		return vars
+	}))
+}
+
+// Publish publishes the "c" limiter's stats under the "name",
+// a limiter already published under the same name is replaced.
+func Publish(name string, c *chronos.C) {
+	mu.Lock()
+	limiters[name] = c
+	mu.Unlock()
+}
+
+// Unpublish removes the limiter which is published under the "name".
+func Unpublish(name string) {
+	mu.Lock()
+	delete(limiters, name)
+	mu.Unlock()
+}
+
+// Names returns the sorted names of the published limiters.
+func Names() []string {
+	mu.RLock()
+	names := make([]string, 0, len(limiters))
+	for name := range limiters {
+		names = append(names, name)
+	}
+	mu.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
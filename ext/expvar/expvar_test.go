@@ -0,0 +1,83 @@
+package expvar
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+func readVars(t *testing.T) map[string]chronos.Stats {
+	v := expvar.Get(VarName)
+	if v == nil {
+		t.Fatalf("expected the %s variable to be published", VarName)
+	}
+
+	var vars map[string]chronos.Stats
+	if err := json.Unmarshal([]byte(v.String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	return vars
+}
+
+func TestPublish(t *testing.T) {
+	c := chronos.New(5, 200*time.Millisecond)
+	Publish("test", c)
+	Publish("unpublished", chronos.New(1, time.Second))
+	Unpublish("unpublished")
+
+	if names := Names(); len(names) != 1 || names[0] != "test" {
+		t.Fatalf("expected only the test limiter to be published but got %v", names)
+	}
+
+	// read the vars while the limiter is in use,
+	// run with --race.
+	var wg sync.WaitGroup
+	for i := 0; i < 7; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-c.Acquire()
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	for {
+		readVars(t)
+		select {
+		case <-done:
+			st := readVars(t)["test"]
+			if st.Max != 5 || st.Per != 200*time.Millisecond || st.Acquired != 7 || st.Waited != 2 || st.Waiting != 0 {
+				t.Fatalf("unexpected published stats: %#+v", st)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestPublishRegistered(t *testing.T) {
+	if err := chronos.Register("test-registered", chronos.New(3, time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	defer chronos.Unregister("test-registered")
+
+	if st, ok := readVars(t)["test-registered"]; !ok || st.Max != 3 {
+		t.Fatalf("expected the registered limiter to be published but got %#+v", st)
+	}
+
+	Publish("test-registered", chronos.New(4, time.Second))
+	defer Unpublish("test-registered")
+
+	if st := readVars(t)["test-registered"]; st.Max != 4 {
+		t.Fatalf("expected the published limiter to take precedence but got %#+v", st)
+	}
+}
@@ -1,8 +1,10 @@
 package function
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/kataras/chronos"
@@ -12,8 +14,32 @@ type Function struct {
 	*chronos.C
 }
 
-func New(max uint32, per time.Duration) *Function {
-	return &Function{C: chronos.New(max, per)}
+// New returns a new Function which allows "max" calls "per" time duration.
+// If the optional "name" is given, the limiter is registered
+// under it, see `chronos.MustRegister`, it panics if the name is in use,
+// use the `NewNamed` to handle that.
+func New(max uint32, per time.Duration, name ...string) *Function {
+	c := chronos.New(max, per)
+	switch len(name) {
+	case 0:
+	case 1:
+		chronos.MustRegister(name[0], c)
+	default:
+		panic("function: expected one name but got: " + strings.Join(name, ", "))
+	}
+
+	return &Function{C: c}
+}
+
+// NewNamed is like `New` but the limiter is always registered under the "name",
+// it returns the `chronos.ErrDuplicateName` if the name is in use.
+func NewNamed(name string, max uint32, per time.Duration) (*Function, error) {
+	c := chronos.New(max, per)
+	if err := chronos.Register(name, c); err != nil {
+		return nil, err
+	}
+
+	return &Function{C: c}, nil
 }
 
 var Panic = func(err error) {
@@ -30,8 +56,19 @@ func (f *Function) MustCall(actionFunc interface{}, actionFuncInput ...interface
 
 func isGoodFunc(fn reflect.Value) bool { return fn.IsValid() && fn.Kind() == reflect.Func }
 
+// Call calls the "actionFunc" with the "actionFuncInput" when the limiter allows it,
+// the returned channel receives its output.
+// It returns `chronos.ErrClosed` if the limiter is closed. If the limiter doesn't allow
+// the call later on, i.e it's closed or paused in the `chronos.PauseReject` mode,
+// the function is not called and the output is the limiter's error, see `LookupError`.
 func (f *Function) Call(actionFunc interface{}, actionFuncInput ...interface{}) (<-chan []reflect.Value, error) {
-	ch := make(chan []reflect.Value, 0)
+	// buffered, the output of an error may not be received.
+	ch := make(chan []reflect.Value, 1)
+
+	if f.C.IsClosed() {
+		ch <- nil
+		return ch, chronos.ErrClosed
+	}
 
 	fn := reflect.ValueOf(actionFunc)
 	if !isGoodFunc(fn) {
@@ -56,6 +93,10 @@ func (f *Function) call(fn reflect.Value, in []reflect.Value, ch chan []reflect.
 		return
 	}
 
-	<-f.C.Acquire()
+	if err := f.C.Wait(context.Background()); err != nil {
+		ch <- []reflect.Value{reflect.ValueOf(err)}
+		return
+	}
+
 	ch <- fn.Call(in)
 }
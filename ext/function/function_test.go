@@ -0,0 +1,50 @@
+package function
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+func TestCallClosed(t *testing.T) {
+	f := New(1, time.Hour)
+
+	called := 0
+	action := func() { called++ }
+
+	if err := AsError(f.Call(action)); err != nil {
+		t.Fatal(err)
+	}
+
+	// waits for the next circle.
+	out := f.MustCall(action)
+	f.Close()
+	if err := LookupError(<-out); err != chronos.ErrClosed {
+		t.Fatalf("expected the waiting call to fail with ErrClosed but got %v", err)
+	}
+
+	if err := AsError(f.Call(action)); err != chronos.ErrClosed {
+		t.Fatalf("expected ErrClosed but got %v", err)
+	}
+
+	if called != 1 {
+		t.Fatalf("expected the function to be called once but called %d times", called)
+	}
+}
+
+func TestNewNamed(t *testing.T) {
+	f, err := NewNamed("test-function", 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chronos.Unregister("test-function")
+
+	if c, ok := chronos.Get("test-function"); !ok || c != f.C {
+		t.Fatalf("expected the limiter to be registered")
+	}
+
+	if _, err = NewNamed("test-function", 1, time.Hour); err != chronos.ErrDuplicateName {
+		t.Fatalf("expected ErrDuplicateName but got %v", err)
+	}
+}
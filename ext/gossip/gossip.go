@@ -0,0 +1,411 @@
+// Package gossip provides a limiter which shares an approximate global limit
+// between peers, without a central store.
+//
+// Every node gossips its demand, the operations it was asked for in the last "per" duration,
+// to a static list of peers over UDP. Each node allows its share of the global "max",
+// scaled by its demand against the demand of the whole cluster,
+// so the aggregate rate converges to "max" operations "per" time duration.
+//
+// The limit is approximate: the peers' demand is known with a delay of the gossip interval,
+// and a peer that stops gossiping is forgotten after two "per" durations.
+package gossip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// slots is the number of the buckets that a "per" duration is divided into,
+// the usage and demand of a node are counted in a sliding window of those buckets.
+const slots = 10
+
+// Config is the configuration of a Node.
+type Config struct {
+	// ID is the unique identifier of the node, defaults to its address.
+	ID string
+	// Addr is the UDP address to listen on, i.e "127.0.0.1:7946".
+	Addr string
+	// Peers are the UDP addresses of the rest nodes.
+	Peers []string
+
+	Max uint32        // maximum operations of the whole cluster
+	Per time.Duration // per x time.
+
+	// Interval is the time between two gossip messages,
+	// defaults to a tenth of the "per" duration.
+	Interval time.Duration
+}
+
+type bucket struct {
+	slot   int64 // the time slot that the bucket counts.
+	usage  uint64
+	demand uint64
+}
+
+type peer struct {
+	addr     *net.UDPAddr
+	demand   uint64
+	usage    uint64
+	reported int64 // unix nanoseconds, zero if never.
+}
+
+// Node is a member of the cluster,
+// it has the same `Acquire` as the `chronos.C`.
+type Node struct {
+	cfg     Config
+	conn    *net.UDPConn
+	started int64
+
+	mu      sync.Mutex
+	buckets [slots]bucket
+	waiting uint64
+	// gossiped is the demand of the last gossip message,
+	// the node computes its share with the same, delayed, view of the cluster as its peers.
+	gossiped uint64
+	peers    map[string]*peer // by address.
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	done      sync.WaitGroup
+}
+
+// ErrInvalidConfig is returned by `Listen` when the "max" is zero,
+// the "per" is shorter than its slots or the "interval" is negative.
+var ErrInvalidConfig = errors.New("gossip: max, per and interval should be positive")
+
+// Listen starts a new Node which listens on the `Config.Addr`
+// and gossips to the `Config.Peers`.
+func Listen(cfg Config) (*Node, error) {
+	// the "per" is divided into slots, each one should be at least a nanosecond.
+	if cfg.Max == 0 || cfg.Per/slots <= 0 || cfg.Interval < 0 {
+		return nil, ErrInvalidConfig
+	}
+
+	if cfg.Interval == 0 {
+		cfg.Interval = cfg.Per / slots
+	}
+
+	laddr, err := net.ResolveUDPAddr("udp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.ID == "" {
+		cfg.ID = conn.LocalAddr().String()
+	}
+
+	n := &Node{
+		cfg:     cfg,
+		conn:    conn,
+		started: time.Now().UnixNano(),
+		peers:   make(map[string]*peer),
+		closed:  make(chan struct{}),
+	}
+
+	if err = n.SetPeers(cfg.Peers...); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	n.done.Add(2)
+	go n.receive()
+	go n.gossip()
+	return n, nil
+}
+
+// Addr returns the address that the node listens on.
+func (n *Node) Addr() string {
+	return n.conn.LocalAddr().String()
+}
+
+// SetPeers replaces the peers of the node.
+func (n *Node) SetPeers(addrs ...string) error {
+	peers := make(map[string]*peer, len(addrs))
+	for _, addr := range addrs {
+		raddr, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			return err
+		}
+		peers[raddr.String()] = &peer{addr: raddr}
+	}
+
+	n.mu.Lock()
+	for addr, p := range n.peers {
+		if _, ok := peers[addr]; ok {
+			peers[addr] = p
+		}
+	}
+	n.peers = peers
+	n.mu.Unlock()
+	return nil
+}
+
+// bucket returns the bucket of the "now" time, it must be called under lock.
+func (n *Node) bucket(now int64) *bucket {
+	slot := now / (int64(n.cfg.Per) / slots)
+	b := &n.buckets[slot%slots]
+	if b.slot != slot {
+		*b = bucket{slot: slot}
+	}
+	return b
+}
+
+// local returns the usage and the demand of the node in the last "per" duration,
+// it must be called under lock.
+func (n *Node) local(now int64) (usage, demand uint64) {
+	slot := now / (int64(n.cfg.Per) / slots)
+	for _, b := range n.buckets {
+		if slot-b.slot < slots {
+			usage += b.usage
+			demand += b.demand
+		}
+	}
+
+	return usage, demand + n.waiting
+}
+
+// allowance returns the operations that this node is allowed to do
+// in the last "per" duration, it must be called under lock.
+func (n *Node) allowance(now int64) uint64 {
+	demand := n.gossiped
+	if demand == 0 {
+		// nothing gossiped yet, use the current one.
+		_, demand = n.local(now)
+	}
+
+	var (
+		total   = demand
+		stale   = now - 2*int64(n.cfg.Per)
+		warming = now-n.started < int64(n.cfg.Per)
+	)
+
+	for _, p := range n.peers {
+		switch {
+		case p.reported > stale:
+			total += p.demand
+		case p.reported == 0 && warming:
+			// we don't know anything about that peer yet,
+			// be fair and consider that it's as busy as we are.
+			total += demand
+		}
+	}
+
+	max := uint64(n.cfg.Max)
+	if total == 0 {
+		return max
+	}
+
+	allowance := max * demand / total
+	if allowance == 0 {
+		allowance = 1
+	}
+	return allowance
+}
+
+// Stats is a snapshot of a node's view of the cluster.
+type Stats struct {
+	Usage        uint64 `json:"usage"`        // operations of the node in the last "per" duration.
+	Demand       uint64 `json:"demand"`       // operations asked from the node in the last "per" duration.
+	Allowance    uint64 `json:"allowance"`    // operations that the node is allowed in a "per" duration.
+	ClusterUsage uint64 `json:"clusterUsage"` // the last known usage of the whole cluster.
+	Peers        int    `json:"peers"`        // the peers that gossiped recently.
+}
+
+// Stats returns the node's view of the cluster.
+func (n *Node) Stats() Stats {
+	now := time.Now().UnixNano()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	usage, demand := n.local(now)
+	st := Stats{
+		Usage:        usage,
+		Demand:       demand,
+		Allowance:    n.allowance(now),
+		ClusterUsage: usage,
+	}
+
+	stale := now - 2*int64(n.cfg.Per)
+	for _, p := range n.peers {
+		if p.reported > stale {
+			st.ClusterUsage += p.usage
+			st.Peers++
+		}
+	}
+
+	return st
+}
+
+// take counts the operation if it's allowed,
+// it must be called under lock.
+func (n *Node) take(now int64) bool {
+	usage, _ := n.local(now)
+	if usage >= n.allowance(now) {
+		return false
+	}
+
+	n.bucket(now).usage++
+	return true
+}
+
+// Allow reports whether an operation is allowed now, it never blocks.
+func (n *Node) Allow() bool {
+	now := time.Now().UnixNano()
+
+	n.mu.Lock()
+	n.bucket(now).demand++
+	ok := n.take(now)
+	n.mu.Unlock()
+	return ok
+}
+
+// Acquire blocks until the operation is allowed by the node's share of the global limit.
+// After `Close` the returned channel is closed without a value,
+// use the `_, ok := <-n.Acquire()` form to check that.
+func (n *Node) Acquire() <-chan struct{} {
+	ch := make(chan struct{}, 1)
+
+	select {
+	case <-n.closed:
+		close(ch)
+		return ch
+	default:
+	}
+
+	now := time.Now().UnixNano()
+	n.mu.Lock()
+	n.bucket(now).demand++
+	if n.take(now) {
+		n.mu.Unlock()
+		ch <- struct{}{}
+		return ch
+	}
+	n.waiting++
+	n.mu.Unlock()
+
+	go n.wait(ch)
+	return ch
+}
+
+func (n *Node) wait(ch chan struct{}) {
+	ticker := time.NewTicker(n.cfg.Per / slots)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.closed:
+			n.mu.Lock()
+			n.waiting--
+			n.mu.Unlock()
+			close(ch)
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().UnixNano()
+		n.mu.Lock()
+		if n.take(now) {
+			n.waiting--
+			n.mu.Unlock()
+			ch <- struct{}{}
+			return
+		}
+		n.mu.Unlock()
+	}
+}
+
+// Close stops the node.
+func (n *Node) Close() error {
+	var err error
+	n.closeOnce.Do(func() {
+		close(n.closed)
+		err = n.conn.Close()
+		n.done.Wait()
+	})
+	return err
+}
+
+// the message format is: "chronos1 <id> <demand> <usage>".
+var messagePrefix = []byte("chronos1")
+
+func (n *Node) gossip() {
+	defer n.done.Done()
+
+	ticker := time.NewTicker(n.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now().UnixNano()
+
+		n.mu.Lock()
+		usage, demand := n.local(now)
+		n.gossiped = demand
+		addrs := make([]*net.UDPAddr, 0, len(n.peers))
+		for _, p := range n.peers {
+			addrs = append(addrs, p.addr)
+		}
+		n.mu.Unlock()
+
+		msg := []byte(fmt.Sprintf("%s %s %d %d", messagePrefix, n.cfg.ID, demand, usage))
+		for _, addr := range addrs {
+			n.conn.WriteToUDP(msg, addr)
+		}
+
+		select {
+		case <-n.closed:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *Node) receive() {
+	defer n.done.Done()
+
+	buf := make([]byte, 512)
+	for {
+		size, addr, err := n.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-n.closed:
+				return
+			default:
+				continue
+			}
+		}
+
+		fields := bytes.Fields(buf[:size])
+		if len(fields) != 4 || !bytes.Equal(fields[0], messagePrefix) {
+			continue
+		}
+
+		demand, err := strconv.ParseUint(string(fields[2]), 10, 64)
+		if err != nil {
+			continue
+		}
+		usage, err := strconv.ParseUint(string(fields[3]), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		n.mu.Lock()
+		// only the static members are accepted.
+		if p, ok := n.peers[addr.String()]; ok {
+			p.demand = demand
+			p.usage = usage
+			p.reported = time.Now().UnixNano()
+		}
+		n.mu.Unlock()
+	}
+}
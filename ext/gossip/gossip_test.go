@@ -0,0 +1,164 @@
+package gossip
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func startCluster(t *testing.T, size int, max uint32, per time.Duration) []*Node {
+	nodes := make([]*Node, size)
+	for i := range nodes {
+		n, err := Listen(Config{Addr: "127.0.0.1:0", Max: max, Per: per})
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[i] = n
+	}
+
+	for i, n := range nodes {
+		var peers []string
+		for j, p := range nodes {
+			if i != j {
+				peers = append(peers, p.Addr())
+			}
+		}
+
+		if err := n.SetPeers(peers...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return nodes
+}
+
+func TestClusterRate(t *testing.T) {
+	const (
+		size            = 3
+		workers         = 4
+		max      uint32 = 30
+		per             = 300 * time.Millisecond
+		duration        = 1500 * time.Millisecond
+	)
+
+	nodes := startCluster(t, size, max, per)
+	defer func() {
+		for _, n := range nodes {
+			n.Close()
+		}
+	}()
+
+	var (
+		total    uint64
+		wg       sync.WaitGroup
+		deadline = time.Now().Add(duration)
+	)
+
+	for _, n := range nodes {
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(n *Node) {
+				defer wg.Done()
+				for {
+					select {
+					case <-n.Acquire():
+					case <-time.After(time.Until(deadline)):
+						return
+					}
+
+					if time.Now().After(deadline) {
+						return
+					}
+					atomic.AddUint64(&total, 1)
+				}
+			}(n)
+		}
+	}
+
+	wg.Wait()
+
+	// a sliding window of "per" allows "max" operations per "per" plus a first burst of "max".
+	ideal := float64(max) * (float64(duration)/float64(per) + 1)
+	const tolerance = 0.25
+
+	got := float64(atomic.LoadUint64(&total))
+	if got > ideal*(1+tolerance) {
+		t.Fatalf("expected at most %.0f operations (%.0f%% tolerance) but got %.0f", ideal*(1+tolerance), tolerance*100, got)
+	}
+
+	if got < ideal/2 {
+		t.Fatalf("expected at least %.0f operations but got %.0f", ideal/2, got)
+	}
+
+	t.Logf("%.0f operations, ideal: %.0f", got, ideal)
+
+	for i, n := range nodes {
+		if st := n.Stats(); st.Peers != size-1 {
+			t.Fatalf("node %d: expected %d peers but got %d", i, size-1, st.Peers)
+		}
+	}
+}
+
+func TestAllowanceScaledByDemand(t *testing.T) {
+	nodes := startCluster(t, 2, 100, time.Second)
+	defer nodes[0].Close()
+	defer nodes[1].Close()
+
+	busy, idle := nodes[0], nodes[1]
+	for i := 0; i < 30; i++ {
+		busy.Allow()
+	}
+	for i := 0; i < 10; i++ {
+		idle.Allow()
+	}
+
+	// wait for a few gossip intervals.
+	time.Sleep(300 * time.Millisecond)
+
+	if got := busy.Stats().Allowance; got != 75 {
+		t.Fatalf("expected the busy node to be allowed 75 operations but got %d", got)
+	}
+
+	if got := idle.Stats().Allowance; got != 25 {
+		t.Fatalf("expected the idle node to be allowed 25 operations but got %d", got)
+	}
+
+	if got := busy.Stats().ClusterUsage; got != 40 {
+		t.Fatalf("expected cluster usage to be 40 but got %d", got)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{Addr: "127.0.0.1:0", Max: 0, Per: time.Second},
+		{Addr: "127.0.0.1:0", Max: 1, Per: 5}, // shorter than its slots.
+		{Addr: "127.0.0.1:0", Max: 1, Per: time.Second, Interval: -time.Second},
+	} {
+		if _, err := Listen(cfg); err != ErrInvalidConfig {
+			t.Fatalf("expected ErrInvalidConfig for %#+v but got %v", cfg, err)
+		}
+	}
+}
+
+func TestAcquireClosed(t *testing.T) {
+	n, err := Listen(Config{Addr: "127.0.0.1:0", Max: 1, Per: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-n.Acquire()
+	waiting := n.Acquire()
+	n.Close()
+
+	for _, ch := range []<-chan struct{}{waiting, n.Acquire()} {
+		select {
+		case _, ok := <-ch:
+			if ok {
+				t.Fatalf("expected acquire to be released with a closed channel")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected acquire to not block after close")
+		}
+	}
+}
@@ -1,15 +1,18 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,15 +22,132 @@ import (
 type Client struct {
 	*chronos.C
 	*http.Client
+
+	// Observer, if not nil, is notified about the time
+	// that each request waited for the limiter, i.e ext/slog.Observer.
+	Observer RequestObserver
+
+	// ResetHeader, if not empty, is the response header which tells when the server
+	// resets its counter, i.e "X-RateLimit-Reset", in unix seconds
+	// or in seconds from now. The limiter is re-aligned to it, see `chronos.C#AlignTo`,
+	// so its `Window` should be a `chronos.Fixed` one.
+	ResetHeader string
+
+	// CostHeader, if not empty, is the response header which tells the actual cost
+	// of a request, i.e "X-Query-Cost" of a points-based API. Each request is charged
+	// the estimated `Cost` and settled with the header's value, see `chronos.Charge#Settle`.
+	CostHeader string
+	// Cost is the estimated cost of a request, defaults to 1.
+	Cost uint32
+
+	// InFlight, if not nil, limits the requests that run at the same time,
+	// a request holds a slot until its response body is closed.
+	// See `chronos.NewConcurrency`.
+	InFlight *chronos.Concurrency
+
+	// Adaptive, if not nil, is fed with the outcome of each request,
+	// from its status code, see `Outcome`. Its limiter should be the Client's one,
+	// see `NewAdaptive`.
+	Adaptive *chronos.Adaptive
 }
 
-func New(max uint32, per time.Duration) *Client {
+// RequestObserver is notified about the time that a request waited for the limiter.
+type RequestObserver interface {
+	OnRequestWait(req *http.Request, wait time.Duration)
+}
+
+// New returns a new Client which allows "max" requests "per" time duration.
+// If the optional "name" is given, the limiter is registered
+// under it, see `chronos.MustRegister`, it panics if the name is in use,
+// use the `NewNamed` to handle that.
+func New(max uint32, per time.Duration, name ...string) *Client {
+	c := newClient(max, per)
+	mustRegister(c.C, name)
+	return c
+}
+
+// NewNamed is like `New` but the limiter is always registered under the "name",
+// it returns the `chronos.ErrDuplicateName` if the name is in use.
+func NewNamed(name string, max uint32, per time.Duration) (*Client, error) {
+	c := newClient(max, per)
+	if err := chronos.Register(name, c.C); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func newClient(max uint32, per time.Duration) *Client {
 	return &Client{
 		C:      chronos.New(max, per),
 		Client: NewTimeoutClient(20 * time.Second),
 	}
 }
 
+// NewAdaptive returns a new Client which finds the limit of the server,
+// it starts with "initial" requests "per" time duration and adapts its limit
+// between the "floor" and the "ceiling" from the status codes, see `chronos.Adaptive`.
+// If the optional "name" is given, the limiter is registered
+// under it, see `chronos.MustRegister`, it panics if the name is in use,
+// use the `NewAdaptiveNamed` to handle that.
+func NewAdaptive(initial, floor, ceiling uint32, per time.Duration, name ...string) *Client {
+	c := newAdaptiveClient(initial, floor, ceiling, per)
+	mustRegister(c.C, name)
+	return c
+}
+
+// NewAdaptiveNamed is like `NewAdaptive` but the limiter is always registered under the "name",
+// it returns the `chronos.ErrDuplicateName` if the name is in use.
+func NewAdaptiveNamed(name string, initial, floor, ceiling uint32, per time.Duration) (*Client, error) {
+	c := newAdaptiveClient(initial, floor, ceiling, per)
+	if err := chronos.Register(name, c.C); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func newAdaptiveClient(initial, floor, ceiling uint32, per time.Duration) *Client {
+	a := chronos.NewAdaptive(initial, floor, ceiling, per)
+	return &Client{
+		C:        a.C,
+		Client:   NewTimeoutClient(20 * time.Second),
+		Adaptive: a,
+	}
+}
+
+// mustRegister registers the "c" under the optional "name",
+// it panics if the name is in use or if more than one names are given.
+func mustRegister(c *chronos.C, name []string) {
+	switch len(name) {
+	case 0:
+	case 1:
+		chronos.MustRegister(name[0], c)
+	default:
+		panic("http: expected one name but got: " + strings.Join(name, ", "))
+	}
+}
+
+// Outcome returns the outcome of a request for an adaptive limiter:
+// a 429 or a 503 status code is a throttling signal, a timeout is a timeout
+// and any other error or status code of 400 and above is ignored.
+func Outcome(resp *http.Response, err error) chronos.Outcome {
+	if err != nil {
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return chronos.OutcomeTimeout
+		}
+		return chronos.OutcomeIgnored
+	}
+
+	switch code := resp.StatusCode; {
+	case code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable:
+		return chronos.OutcomeThrottled
+	case code >= 400:
+		return chronos.OutcomeIgnored
+	default:
+		return chronos.OutcomeSuccess
+	}
+}
+
 // Do sends an HTTP request and returns an HTTP response, following
 // policy (such as redirects, cookies, auth) as configured on the
 // client.
@@ -61,9 +181,107 @@ func New(max uint32, per time.Duration) *Client {
 // provided that the Request.GetBody function is defined.
 // The NewRequest function automatically sets GetBody for common
 // standard library body types.
+//
+// The request waits for the limiter until its context is done,
+// it fails with `chronos.ErrClosed` if the limiter is closed
+// and with `chronos.ErrQueueFull` if the limiter's `QueueSize` is reached.
+// Each request counts as its `Cost`, see `CostHeader`, a request which
+// failed before it reached the server, i.e a DNS error, is refunded.
+// If the `Adaptive` is set, it's fed with the outcome of the request.
+// If the `InFlight` is set, the request waits for a free slot first
+// and it holds it until the response body is closed.
 func (hc *Client) Do(req *http.Request) (*http.Response, error) {
-	<-hc.C.Acquire() // <-- the only part that this differs, except the ReadJSON/XML.
-	return hc.Client.Do(req)
+	start := time.Now()
+	cost := hc.Cost
+	if cost == 0 {
+		cost = 1
+	}
+	release := func() {}
+	if hc.InFlight != nil {
+		// the slot first, so the rate is not spent while waiting for a slot.
+		var err error
+		if release, err = hc.InFlight.Acquire(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	charge, err := hc.C.WaitCost(req.Context(), cost)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if hc.Observer != nil {
+		hc.Observer.OnRequestWait(req, time.Since(start))
+	}
+
+	resp, err := hc.Client.Do(req)
+	if hc.Adaptive != nil {
+		hc.Adaptive.Report(Outcome(resp, err))
+	}
+	if err != nil {
+		release()
+		if notSent(err) {
+			charge.Refund()
+		}
+		return resp, err
+	}
+
+	if hc.InFlight != nil {
+		resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	}
+
+	if hc.ResetHeader != "" {
+		if reset, ok := parseReset(resp.Header.Get(hc.ResetHeader)); ok {
+			hc.C.AlignTo(reset)
+		}
+	}
+
+	if hc.CostHeader != "" {
+		if actual, err := strconv.ParseUint(resp.Header.Get(hc.CostHeader), 10, 32); err == nil {
+			charge.Settle(uint32(actual))
+		}
+	}
+
+	return resp, nil
+}
+
+// releaseBody releases the in-flight slot of a request when its response body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// notSent reports whether the request failed before it reached the server,
+// i.e a DNS or a connection error, so it's not counted by the server.
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// parseReset parses a reset header value, the values before 2001 (1e9 seconds)
+// are considered as seconds from now.
+func parseReset(v string) (time.Time, bool) {
+	seconds, err := strconv.ParseFloat(v, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}, false
+	}
+
+	if seconds < 1e9 {
+		return time.Now().Add(time.Duration(seconds * float64(time.Second))), true
+	}
+
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
 }
 
 // Head issues a HEAD to the specified URL. If the response is one of the
@@ -213,6 +431,65 @@ func (hc *Client) unmarshalBody(body io.ReadCloser, v interface{}, unmarshaler U
 	return unmarshaler.Unmarshal(rawData, &v)
 }
 
+// StatusError is returned by `Read` when the server responds with an error status code,
+// use `errors.As` to read it.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+	// Body is the start of the response body, up to the `MaxStatusErrorBody` bytes.
+	Body []byte
+	// RetryAfter is the duration of the "Retry-After" header, if any.
+	RetryAfter time.Duration
+}
+
+// MaxStatusErrorBody is the maximum bytes of the response body that a `StatusError` keeps.
+var MaxStatusErrorBody int64 = 512
+
+func newStatusError(resp *http.Response) *StatusError {
+	err := &StatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	if req := resp.Request; req != nil {
+		err.Method = req.Method
+		err.URL = req.URL.String()
+	}
+
+	err.Body, _ = ioutil.ReadAll(io.LimitReader(resp.Body, MaxStatusErrorBody))
+	return err
+}
+
+// parseRetryAfter parses the "Retry-After" header value,
+// which is either seconds or an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseInt(v, 10, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status code %d when fetching '%s'", e.StatusCode, e.URL)
+}
+
 // ReadJSON will fill the "v" from the GET: "url" body's content based on the "unmarshaler".
 func (hc *Client) Read(url string, v interface{}, unmarshaler Unmarshaler) error {
 	resp, err := hc.Get(url)
@@ -223,7 +500,7 @@ func (hc *Client) Read(url string, v interface{}, unmarshaler Unmarshaler) error
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("status code %d when fetching '%s'", resp.StatusCode, url)
+		return newStatusError(resp)
 	}
 
 	return hc.unmarshalBody(resp.Body, v, unmarshaler)
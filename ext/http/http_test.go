@@ -0,0 +1,183 @@
+package http
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+func TestStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte("slow down"))
+	}))
+	defer srv.Close()
+
+	hc := New(10, time.Second)
+	var v map[string]interface{}
+	err := hc.ReadJSON(srv.URL, &v)
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected a *StatusError but got %v", err)
+	}
+
+	if statusErr.StatusCode != http.StatusTooManyRequests || statusErr.Method != http.MethodGet ||
+		statusErr.URL != srv.URL || string(statusErr.Body) != "slow down" || statusErr.RetryAfter != 3*time.Second {
+		t.Fatalf("unexpected status error: %#+v", statusErr)
+	}
+}
+
+func TestNewNamed(t *testing.T) {
+	hc, err := NewNamed("test-http", 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chronos.Unregister("test-http")
+
+	if c, ok := chronos.Get("test-http"); !ok || c != hc.C {
+		t.Fatalf("expected the limiter to be registered")
+	}
+
+	if _, err = NewAdaptiveNamed("test-http", 10, 2, 20, time.Hour); err != chronos.ErrDuplicateName {
+		t.Fatalf("expected ErrDuplicateName but got %v", err)
+	}
+}
+
+func TestDoClosed(t *testing.T) {
+	hc := New(10, time.Second)
+	hc.Close()
+
+	if _, err := hc.Get("http://localhost"); !errors.Is(err, chronos.ErrClosed) {
+		t.Fatalf("expected chronos.ErrClosed but got %v", err)
+	}
+}
+
+func TestResetHeader(t *testing.T) {
+	reset := time.Now().Truncate(time.Second).Add(17 * time.Second)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	}))
+	defer srv.Close()
+
+	window := chronos.NewFixed(time.Minute, 0)
+	hc := New(10, 0)
+	hc.C.Window = window
+	hc.ResetHeader = "X-RateLimit-Reset"
+
+	resp, err := hc.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if _, end := window.Bounds(time.Now()); !end.Equal(reset) {
+		t.Fatalf("expected the window to end at %s but got %s", reset, end)
+	}
+}
+
+func TestCostHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Query-Cost", "3")
+	}))
+	defer srv.Close()
+
+	hc := New(10, time.Hour)
+	hc.CostHeader = "X-Query-Cost"
+	hc.Cost = 5
+
+	resp, err := hc.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if st := hc.Stats(); st.Length != 3 {
+		t.Fatalf("expected the request to be settled with its actual cost of 3 but got %d", st.Length)
+	}
+}
+
+func TestDoRefund(t *testing.T) {
+	// a closed port, the connection is refused.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	hc := New(1, time.Hour)
+	if _, err = hc.Get("http://" + addr); err == nil {
+		t.Fatalf("expected a connection error")
+	}
+
+	if st := hc.Stats(); st.Length != 0 || st.Remaining != 1 {
+		t.Fatalf("expected the failed request to be refunded but got %#+v", st)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	hc := New(10, time.Hour)
+	hc.InFlight = chronos.NewConcurrency(1)
+
+	resp, err := hc.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := hc.Get(srv.URL)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("expected the second request to wait for the first one's body to be closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	resp.Body.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the second request to be sent after the first one's body is closed")
+	}
+
+	if st := hc.InFlight.Stats(); st.InFlight != 0 || st.Acquired != 2 {
+		t.Fatalf("unexpected in-flight stats: %#+v", st)
+	}
+}
+
+func TestAdaptive(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	hc := NewAdaptive(10, 2, 20, time.Minute)
+	resp, err := hc.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if limit := hc.Adaptive.Limit(); limit != 5 || hc.Stats().Max != 5 {
+		t.Fatalf("expected the limit to be decreased to 5 but got %d", limit)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package mmap
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrUnsupported is returned by `Open` on platforms without `mmap` and `flock`.
+var ErrUnsupported = errors.New("mmap: unsupported platform")
+
+type file struct{}
+
+// Open is not supported on this platform, it returns `ErrUnsupported`.
+func Open(filename string, max uint32, per time.Duration) (*Limiter, error) {
+	return nil, ErrUnsupported
+}
+
+func (f *file) do(fn func(data []byte)) error { return ErrUnsupported }
+
+func (f *file) close() error { return nil }
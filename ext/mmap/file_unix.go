@@ -0,0 +1,119 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package mmap
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// file is the memory-mapped file, its accesses are serialized
+// between the goroutines by the mutex and between the processes by the `flock`.
+type file struct {
+	mu   sync.Mutex
+	f    *os.File
+	data []byte
+}
+
+// Open opens, or creates, the "filename" shared state and returns a new Limiter
+// which allows "max" operations "per" time duration across all processes
+// which open the same file.
+//
+// All processes should use the same "max" and "per",
+// each process enforces its own configuration against the shared state.
+func Open(filename string, max uint32, per time.Duration) (*Limiter, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = flock(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	data, err := initFile(f, max)
+	funlock(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return newLimiter(max, per, &file{f: f, data: data}), nil
+}
+
+// initFile must be called under the `flock`.
+func initFile(f *os.File, max uint32) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() < fileSize {
+		if err = f.Truncate(fileSize); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, fileSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isInitialized(data) {
+		writeState(data, state{})
+	} else if s, ok := readState(data); !ok {
+		writeState(data, recoverState(s, max, time.Now().UnixNano()))
+	}
+
+	return data, nil
+}
+
+func flock(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+func funlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+func (f *file) do(fn func(data []byte)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.data == nil {
+		return ErrClosed
+	}
+
+	if err := flock(f.f); err != nil {
+		return err
+	}
+
+	fn(f.data)
+	return funlock(f.f)
+}
+
+func (f *file) close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.data == nil {
+		return nil
+	}
+
+	err := syscall.Munmap(f.data)
+	f.data = nil
+	if closeErr := f.f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,197 @@
+// Package mmap provides a limiter which is shared between processes on the same host.
+//
+// Every process which opens the same file shares the same limit,
+// the state lives in a memory-mapped file and it's coordinated through `flock`.
+//
+// Crash safety: the `flock` is owned by the open file, the kernel releases it
+// when the process exits or crashes, so a dead process can't leave a stale lock behind.
+// The state is protected by a checksum, a process that crashed in the middle of a write
+// leaves a state which doesn't match its checksum, the next process recovers it
+// by considering the current window as full, it's the fair choice for the remote service.
+package mmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"sync"
+	"time"
+)
+
+// The layout of the shared file, all integers are little endian.
+const (
+	offMagic     = 0  // 8 bytes.
+	offCircle    = 8  // uint64.
+	offLength    = 16 // uint32.
+	offLastAdded = 24 // int64, unix nanoseconds.
+	offChecksum  = 32 // uint32, crc32 of [offCircle, offChecksum).
+
+	fileSize = 64
+)
+
+var magic = []byte("CHRONOS1")
+
+// ErrClosed is returned when the limiter is used after `Close`.
+var ErrClosed = errors.New("mmap: limiter is closed")
+
+// state is the shared part of the limiter.
+type state struct {
+	circle    uint64
+	length    uint32
+	lastAdded int64
+}
+
+func checksum(b []byte) uint32 {
+	return crc32.ChecksumIEEE(b[offCircle:offChecksum])
+}
+
+func isInitialized(b []byte) bool {
+	return string(b[offMagic:offMagic+len(magic)]) == string(magic)
+}
+
+// readState reads the state from "b", it reports false
+// if the state is corrupted.
+func readState(b []byte) (state, bool) {
+	s := state{
+		circle:    binary.LittleEndian.Uint64(b[offCircle:]),
+		length:    binary.LittleEndian.Uint32(b[offLength:]),
+		lastAdded: int64(binary.LittleEndian.Uint64(b[offLastAdded:])),
+	}
+
+	return s, binary.LittleEndian.Uint32(b[offChecksum:]) == checksum(b)
+}
+
+func writeState(b []byte, s state) {
+	copy(b[offMagic:], magic)
+	binary.LittleEndian.PutUint64(b[offCircle:], s.circle)
+	binary.LittleEndian.PutUint32(b[offLength:], s.length)
+	binary.LittleEndian.PutUint64(b[offLastAdded:], uint64(s.lastAdded))
+	binary.LittleEndian.PutUint32(b[offChecksum:], checksum(b))
+}
+
+// take is the same algorithm as the `chronos.C`'s one.
+// It returns the new state and zero if the operation is allowed
+// or the duration that should be waited before retry.
+func take(s state, max uint32, per int64, now int64) (state, int64) {
+	if s.lastAdded != 0 && now-s.lastAdded-per > 0 {
+		s.circle++
+		s.length = 0
+	}
+
+	if s.length < max {
+		s.length++
+		s.lastAdded = now
+		return s, 0
+	}
+
+	sched := now - s.lastAdded
+	if sched <= per {
+		sched = per - sched
+	}
+
+	if sched <= 0 {
+		// the window just finished, retry as soon as possible.
+		sched = 1
+	}
+
+	return s, sched
+}
+
+// recoverState returns the state that should be used when
+// the shared one is corrupted.
+func recoverState(s state, max uint32, now int64) state {
+	s.circle++
+	s.length = max
+	s.lastAdded = now
+	return s
+}
+
+// Limiter is a limiter which its state is shared across processes.
+// It has the same `Acquire` as the `chronos.C`.
+type Limiter struct {
+	Max uint32 // maximum operations
+	Per int64  // per x time (in nanoseconds).
+
+	f         *file
+	closeOnce sync.Once
+	closed    chan struct{} // closed on `Close`, it releases the waiting `Acquire` calls.
+}
+
+func newLimiter(max uint32, per time.Duration, f *file) *Limiter {
+	return &Limiter{
+		Max:    max,
+		Per:    int64(per),
+		f:      f,
+		closed: make(chan struct{}),
+	}
+}
+
+// Acquire blocks if the already called times, from any process which shares
+// the same file, are > than the given "max" operations.
+//
+// After `Close`, or if the shared file can't be locked, the returned channel
+// is closed without a value, use the `_, ok := <-l.Acquire()` form to check that.
+func (l *Limiter) Acquire() <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	go l.acquire(ch)
+	return ch
+}
+
+func (l *Limiter) acquire(ch chan struct{}) {
+	for {
+		sched, err := l.tryAcquire()
+		if err != nil {
+			// closed or the file can't be locked, release the caller.
+			close(ch)
+			return
+		}
+
+		if sched == 0 {
+			ch <- struct{}{}
+			return
+		}
+
+		timer := time.NewTimer(time.Duration(sched))
+		select {
+		case <-l.closed:
+			timer.Stop()
+			close(ch)
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *Limiter) tryAcquire() (sched int64, err error) {
+	err = l.f.do(func(b []byte) {
+		now := time.Now().UnixNano()
+		s, ok := readState(b)
+		if !ok {
+			s = recoverState(s, l.Max, now)
+		}
+
+		s, sched = take(s, l.Max, l.Per, now)
+		writeState(b, s)
+	})
+
+	return
+}
+
+// Circle returns the current "circle" of the shared state.
+func (l *Limiter) Circle() (circle uint64) {
+	l.f.do(func(b []byte) {
+		s, _ := readState(b)
+		circle = s.circle
+	})
+
+	return
+}
+
+// Close releases the memory-mapped file,
+// the state is kept in the file for the rest processes.
+func (l *Limiter) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+	return l.f.close()
+}
@@ -0,0 +1,193 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package mmap
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	helperEnv = "CHRONOS_MMAP_HELPER"
+
+	testMax uint32 = 2
+	testPer        = 300 * time.Millisecond
+
+	children = 3
+	acquires = 3
+)
+
+// TestHelperProcess is not a real test, it's the child process
+// of the `TestSharedAcquire`, it prints the time of each acquire.
+func TestHelperProcess(t *testing.T) {
+	filename := os.Getenv(helperEnv)
+	if filename == "" {
+		return
+	}
+
+	l, err := Open(filename, testMax, testPer)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for i := 0; i < acquires; i++ {
+		<-l.Acquire()
+		fmt.Println(time.Now().UnixNano())
+	}
+
+	l.Close()
+	os.Exit(0)
+}
+
+func tempFile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "chronos-mmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, "shared.limiter"), func() { os.RemoveAll(dir) }
+}
+
+func TestSharedAcquire(t *testing.T) {
+	filename, cleanup := tempFile(t)
+	defer cleanup()
+
+	cmds := make([]*exec.Cmd, children)
+	outputs := make([]*bytes.Buffer, children)
+	for i := range cmds {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+		cmd.Env = append(os.Environ(), helperEnv+"="+filename)
+		outputs[i] = new(bytes.Buffer)
+		cmd.Stdout = outputs[i]
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds[i] = cmd
+	}
+
+	var fired []int64
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("child %d: %v", i, err)
+		}
+
+		scanner := bufio.NewScanner(outputs[i])
+		for scanner.Scan() {
+			n, err := strconv.ParseInt(scanner.Text(), 10, 64)
+			if err != nil {
+				t.Fatalf("child %d: %v", i, err)
+			}
+			fired = append(fired, n)
+		}
+	}
+
+	if expected, got := children*acquires, len(fired); expected != got {
+		t.Fatalf("expected %d acquires but got %d", expected, got)
+	}
+
+	sort.Slice(fired, func(i, j int) bool { return fired[i] < fired[j] })
+
+	// no more than "max" operations should be fired inside a "per" duration,
+	// the operation max+1 places after should be fired at least "per" later.
+	const tolerance = int64(20 * time.Millisecond)
+	for i := 0; i+int(testMax) < len(fired); i++ {
+		if elapsed := fired[i+int(testMax)] - fired[i]; elapsed < int64(testPer)-tolerance {
+			t.Fatalf("acquire %d fired %s after acquire %d, expected at least %s", i+int(testMax), time.Duration(elapsed), i, testPer)
+		}
+	}
+}
+
+func TestRecoverCorruptedState(t *testing.T) {
+	filename, cleanup := tempFile(t)
+	defer cleanup()
+
+	l, err := Open(filename, testMax, testPer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-l.Acquire()
+	l.Close()
+
+	// simulate a process which crashed in the middle of a write.
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[offLength]++
+	if err = ioutil.WriteFile(filename, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open(filename, testMax, testPer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if expected, got := uint64(1), l.Circle(); expected != got {
+		t.Fatalf("expected circle %d after recovery but got %d", expected, got)
+	}
+
+	// the window should be considered as full.
+	now := time.Now()
+	<-l.Acquire()
+	if since := time.Since(now); since < testPer/2 {
+		t.Fatalf("expected acquire to wait after recovery but fired after %s", since)
+	}
+}
+
+func TestAcquireClosed(t *testing.T) {
+	filename, cleanup := tempFile(t)
+	defer cleanup()
+
+	l, err := Open(filename, testMax, testPer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	select {
+	case _, ok := <-l.Acquire():
+		if ok {
+			t.Fatalf("expected acquire to be released with a closed channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected acquire to not block after close")
+	}
+}
+
+func TestAcquireWaitingClosed(t *testing.T) {
+	filename, cleanup := tempFile(t)
+	defer cleanup()
+
+	l, err := Open(filename, 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-l.Acquire()
+
+	ch := l.Acquire() // waits an hour.
+	time.Sleep(50 * time.Millisecond)
+	l.Close()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatalf("expected the waiting acquire to be released with a closed channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the waiting acquire to be released on close")
+	}
+}
@@ -0,0 +1,171 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+// Client is a named limiter of a remote sidecar `Server`.
+// It implements the `chronos.Limiter` interface, so it can be used
+// everywhere a `chronos.C` is used.
+//
+// The methods of the `chronos.Limiter` interface can't report errors,
+// use their `Context` versions to handle them.
+type Client struct {
+	BaseURL string // i.e http://localhost:8080
+	Name    string // the limiter's name.
+
+	HTTPClient *http.Client
+	// RetryInterval is the time that `Acquire` waits
+	// before retry when the server is unreachable.
+	RetryInterval time.Duration
+}
+
+var _ chronos.Limiter = (*Client)(nil)
+
+// NewClient returns a new Client for the limiter
+// which is registered under the "name" on the "baseURL" sidecar server.
+func NewClient(baseURL, name string) *Client {
+	return &Client{
+		BaseURL:       strings.TrimSuffix(baseURL, "/"),
+		Name:          name,
+		HTTPClient:    http.DefaultClient,
+		RetryInterval: time.Second,
+	}
+}
+
+// StatusError is the error of a request that the server failed with a status code.
+type StatusError struct {
+	Path       string
+	StatusCode int
+	Message    string // the error of the `ErrorResponse`.
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("sidecar: %s: status code %d: %s", e.Path, e.StatusCode, e.Message)
+}
+
+// temporary reports whether a request which failed with the "err" may succeed if it's retried,
+// that's true for the transport errors and the 5xx status codes.
+func temporary(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+func (c *Client) do(ctx context.Context, path string, v interface{}) error {
+	body, err := json.Marshal(Request{Name: c.Name})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return &StatusError{Path: path, StatusCode: resp.StatusCode, Message: errResp.Error}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// AcquireContext blocks until the operation is allowed by the server
+// or the "ctx" is done.
+func (c *Client) AcquireContext(ctx context.Context) error {
+	var resp Response
+	return c.do(ctx, PathAcquire, &resp)
+}
+
+// AllowContext reports whether the operation is allowed now.
+func (c *Client) AllowContext(ctx context.Context) (bool, error) {
+	var resp Response
+	err := c.do(ctx, PathAllow, &resp)
+	return resp.Allowed, err
+}
+
+// ReserveContext counts the operation and returns the duration
+// that the caller should wait before execute it.
+func (c *Client) ReserveContext(ctx context.Context) (time.Duration, error) {
+	var resp Response
+	err := c.do(ctx, PathReserve, &resp)
+	return resp.Wait, err
+}
+
+// StatsContext returns the limiter's stats.
+func (c *Client) StatsContext(ctx context.Context) (chronos.Stats, error) {
+	var st chronos.Stats
+	err := c.do(ctx, PathStats, &st)
+	return st, err
+}
+
+// Acquire blocks until the operation is allowed by the server.
+// On transport errors and 5xx status codes it retries every `RetryInterval`,
+// on the rest errors, i.e a 404 of a limiter that's not registered,
+// the returned channel is closed without a value.
+func (c *Client) Acquire() <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	go func() {
+		for {
+			err := c.AcquireContext(context.Background())
+			if err == nil {
+				ch <- struct{}{}
+				return
+			}
+
+			if !temporary(err) {
+				close(ch)
+				return
+			}
+
+			time.Sleep(c.RetryInterval)
+		}
+	}()
+	return ch
+}
+
+// Allow reports whether the operation is allowed now,
+// an error is reported as not allowed.
+func (c *Client) Allow() bool {
+	ok, err := c.AllowContext(context.Background())
+	return ok && err == nil
+}
+
+// Reserve counts the operation and returns the duration that the caller
+// should wait before execute it, on error it returns the `chronos.Never`
+// as the operation is not counted.
+func (c *Client) Reserve() time.Duration {
+	wait, err := c.ReserveContext(context.Background())
+	if err != nil {
+		return chronos.Never
+	}
+	return wait
+}
+
+// Stats returns the limiter's stats, on error it returns empty stats.
+func (c *Client) Stats() chronos.Stats {
+	st, _ := c.StatsContext(context.Background())
+	return st
+}
@@ -0,0 +1,103 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Duration is a `time.Duration` which can be decoded from
+// a JSON string, i.e "1m30s", or from a number of nanoseconds.
+type Duration time.Duration
+
+// MarshalJSON implements the `json.Marshaler` interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements the `json.Unmarshaler` interface.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case float64:
+		*d = Duration(value)
+	case string:
+		dur, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = Duration(dur)
+	default:
+		return fmt.Errorf("invalid duration %s", b)
+	}
+
+	return nil
+}
+
+// LimiterConfig is the configuration of a named limiter,
+// X "max" operations "per" Y time duration.
+type LimiterConfig struct {
+	Max uint32   `json:"max"`
+	Per Duration `json:"per"`
+	// DryRun enables the dry-run mode of the limiter,
+	// its limit is reported but not enforced, see `chronos.C#SetDryRun`.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// Config is the configuration of the sidecar server.
+//
+// Example JSON:
+//
+//	{
+//	    "addr": ":8080",
+//	    "respAddr": ":6380",
+//	    "limiters": {
+//	        "github": { "max": 5000, "per": "1h" },
+//	        "ip-api": { "max": 150, "per": "1m" },
+//	        "search": { "max": 10, "per": "1s", "dryRun": true }
+//	    }
+//	}
+type Config struct {
+	Addr string `json:"addr"`
+	// RESPAddr is the address of the Redis protocol frontend,
+	// empty means that it's disabled.
+	RESPAddr string                   `json:"respAddr"`
+	Limiters map[string]LimiterConfig `json:"limiters"`
+}
+
+// Validate reports an error if a limiter is misconfigured.
+func (cfg Config) Validate() error {
+	for name, l := range cfg.Limiters {
+		if name == "" {
+			return errors.New("sidecar: empty limiter name")
+		}
+
+		if l.Max == 0 || l.Per <= 0 {
+			return fmt.Errorf("sidecar: limiter '%s': max and per should be positive", name)
+		}
+	}
+
+	return nil
+}
+
+// LoadConfig reads and validates a JSON configuration file.
+func LoadConfig(filename string) (Config, error) {
+	var cfg Config
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return cfg, err
+	}
+
+	if err = json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("sidecar: config '%s': %v", filename, err)
+	}
+
+	return cfg, cfg.Validate()
+}
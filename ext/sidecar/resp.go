@@ -0,0 +1,349 @@
+package sidecar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+// ServeRESP accepts connections on the "l" listener and serves the RESP (Redis protocol) API,
+// it blocks until the listener is closed, see `Close`.
+//
+// Supported commands:
+//
+//	PING [message]
+//	QUIT
+//	CL.THROTTLE key max_burst count period [quantity]
+//
+// The CL.THROTTLE replies like the redis-cell module does, with an array of five integers:
+//
+//  1. 0 if the operation is allowed, 1 if it's limited.
+//  2. The total limit of the key (max_burst + 1).
+//  3. The remaining limit of the key.
+//  4. The number of seconds until the caller should retry, -1 if allowed.
+//  5. The number of seconds until the limit will reset to its maximum capacity.
+//
+// If the key is a configured limiter then that limiter is used and the rest arguments are ignored,
+// otherwise a limiter of "max_burst + 1" operations is created on the first call for that key,
+// its "per" duration keeps the same average rate of "count" operations per "period" seconds.
+// The limit of the key follows the arguments of its last call.
+// Those limiters are kept apart from the configured ones and they are evicted when they are idle
+// for their "per" duration, then their state is the same as a new one's.
+// Up to `MaxThrottleKeys` are kept, the calls for new keys fail after that.
+func (s *Server) ServeRESP(l net.Listener) error {
+	s.respMu.Lock()
+	if s.closed {
+		s.respMu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.respListeners[l] = struct{}{}
+	s.respMu.Unlock()
+
+	defer func() {
+		s.respMu.Lock()
+		delete(s.respListeners, l)
+		s.respMu.Unlock()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.respMu.Lock()
+			closed := s.closed
+			s.respMu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.respMu.Lock()
+		if s.closed {
+			s.respMu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.respConns[conn] = struct{}{}
+		s.respMu.Unlock()
+
+		go s.serveRESPConn(conn)
+	}
+}
+
+// ListenAndServeRESP listens on the TCP network address "addr" and calls `ServeRESP`.
+func (s *Server) ListenAndServeRESP(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.ServeRESP(l)
+}
+
+// Close closes the RESP listeners and their connections,
+// the HTTP API is not affected, it's closed by its own `http.Server`.
+func (s *Server) Close() error {
+	s.respMu.Lock()
+	defer s.respMu.Unlock()
+
+	s.closed = true
+	for l := range s.respListeners {
+		l.Close()
+	}
+	for conn := range s.respConns {
+		conn.Close()
+	}
+
+	return nil
+}
+
+func (s *Server) serveRESPConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.respMu.Lock()
+		delete(s.respConns, conn)
+		s.respMu.Unlock()
+	}()
+
+	r := bufio.NewReaderSize(conn, maxRESPLine)
+	w := bufio.NewWriter(conn)
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if err != io.EOF {
+				writeRESPError(w, err.Error())
+				w.Flush()
+			}
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := s.execRESP(w, args)
+		if err = w.Flush(); err != nil || quit {
+			return
+		}
+	}
+}
+
+func (s *Server) execRESP(w *bufio.Writer, args []string) (quit bool) {
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		if len(args) > 1 {
+			writeRESPBulk(w, args[1])
+		} else {
+			w.WriteString("+PONG\r\n")
+		}
+	case "QUIT":
+		w.WriteString("+OK\r\n")
+		return true
+	case "CL.THROTTLE":
+		s.throttle(w, args[1:])
+	default:
+		writeRESPError(w, fmt.Sprintf("unknown command '%s'", args[0]))
+	}
+
+	return false
+}
+
+func (s *Server) throttle(w *bufio.Writer, args []string) {
+	if len(args) != 4 && len(args) != 5 {
+		writeRESPError(w, "wrong number of arguments for 'cl.throttle' command")
+		return
+	}
+
+	var nums [4]int64
+	nums[3] = 1 // quantity.
+	for i, arg := range args[1:] {
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || n < 0 {
+			writeRESPError(w, "value is not a positive integer or out of range")
+			return
+		}
+		nums[i] = n
+	}
+
+	maxBurst, count, period, quantity := nums[0], nums[1], nums[2], nums[3]
+	if count == 0 || period == 0 || maxBurst+1 > math.MaxUint32 || quantity > math.MaxUint32 {
+		writeRESPError(w, "invalid cl.throttle arguments")
+		return
+	}
+
+	max := uint32(maxBurst + 1)
+	per := time.Duration(float64(period) * float64(time.Second) * float64(max) / float64(count))
+	if per <= 0 {
+		per = 1
+	}
+
+	c, ok := s.throttleLimiter(args[0], max, per)
+	if !ok {
+		writeRESPError(w, "too many cl.throttle keys")
+		return
+	}
+
+	limited, retryAfter := int64(0), int64(-1)
+	if !c.AllowN(uint32(quantity)) {
+		limited = 1
+	}
+
+	st := c.Stats()
+	resetAfter := ceilSeconds(st.ResetAfter)
+	if limited == 1 && uint32(quantity) <= st.Max {
+		retryAfter = resetAfter
+	}
+
+	writeRESPIntegers(w, limited, int64(st.Max), int64(st.Remaining), retryAfter, resetAfter)
+}
+
+// DefaultMaxThrottleKeys is the default `Server.MaxThrottleKeys`.
+const DefaultMaxThrottleKeys = 100000
+
+// throttle is the limiter of a CL.THROTTLE key which is not a configured limiter.
+type throttle struct {
+	c    *chronos.C
+	max  uint32
+	per  time.Duration
+	used int64 // unix nanoseconds.
+}
+
+// throttleSweepInterval is the minimum time between two evictions of the idle throttles.
+const throttleSweepInterval = int64(time.Second)
+
+// throttleLimiter returns the configured limiter of the "key"
+// or its throttle limiter of "max" operations "per" time duration.
+// It reports false if the key is new and the `MaxThrottleKeys` is reached.
+func (s *Server) throttleLimiter(key string, max uint32, per time.Duration) (*chronos.C, bool) {
+	if c, ok := s.Limiter(key); ok {
+		return c, true
+	}
+
+	now := time.Now().UnixNano()
+
+	s.throttleMu.Lock()
+	defer s.throttleMu.Unlock()
+
+	if now-s.throttleSwept > throttleSweepInterval {
+		s.throttleSwept = now
+		for k, t := range s.throttles {
+			if now-t.used > int64(t.per) {
+				delete(s.throttles, k)
+			}
+		}
+	}
+
+	t, ok := s.throttles[key]
+	if !ok {
+		maxKeys := s.MaxThrottleKeys
+		if maxKeys <= 0 {
+			maxKeys = DefaultMaxThrottleKeys
+		}
+		if len(s.throttles) >= maxKeys {
+			return nil, false
+		}
+
+		t = &throttle{c: chronos.New(max, per), max: max, per: per}
+		s.throttles[key] = t
+	} else if t.max != max || t.per != per {
+		t.c.SetLimit(max, per)
+		t.max, t.per = max, per
+	}
+
+	t.used = now
+	return t.c, true
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// ErrServerClosed is returned by the `ServeRESP` after a call to `Close`.
+var ErrServerClosed = errors.New("sidecar: server closed")
+
+var errProtocol = errors.New("Protocol error")
+
+// readCommand reads a RESP array of bulk strings or an inline command.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > 1024 {
+		return nil, errProtocol
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err = readLine(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol
+		}
+
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > 512*1024 {
+			return nil, errProtocol
+		}
+
+		b := make([]byte, size+2)
+		if _, err = io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+
+	return args, nil
+}
+
+// maxRESPLine is the maximum length of a line, i.e an inline command.
+const maxRESPLine = 64 * 1024
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		switch {
+		case err == bufio.ErrBufferFull:
+			return "", errProtocol
+		case err == io.EOF && len(line) > 0:
+			return "", io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+func writeRESPError(w *bufio.Writer, msg string) {
+	w.WriteString("-ERR " + msg + "\r\n")
+}
+
+func writeRESPBulk(w *bufio.Writer, s string) {
+	w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func writeRESPIntegers(w *bufio.Writer, nums ...int64) {
+	w.WriteString("*" + strconv.Itoa(len(nums)) + "\r\n")
+	for _, n := range nums {
+		w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+	}
+}
@@ -0,0 +1,220 @@
+package sidecar
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// respClient is a minimal RESP client.
+type respClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *respClient) do(args ...string) (interface{}, error) {
+	cmd := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		cmd += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+
+	if _, err := c.conn.Write([]byte(cmd)); err != nil {
+		return nil, err
+	}
+
+	return c.read()
+}
+
+func (c *respClient) read() (interface{}, error) {
+	line, err := readLine(c.r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("%s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, _ := strconv.Atoi(line[1:])
+		b := make([]byte, size+2)
+		_, err = c.r.Read(b)
+		return string(b[:size]), err
+	case '*':
+		n, _ := strconv.Atoi(line[1:])
+		values := make([]interface{}, n)
+		for i := range values {
+			if values[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply: %s", line)
+	}
+}
+
+func newRESPTest(t *testing.T) (*Server, *respClient) {
+	s, err := NewServer(Config{
+		Limiters: map[string]LimiterConfig{
+			"configured": {Max: 1, Per: Duration(time.Minute)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.ServeRESP(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, &respClient{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func throttleReply(values ...int64) []interface{} {
+	reply := make([]interface{}, len(values))
+	for i, v := range values {
+		reply[i] = v
+	}
+	return reply
+}
+
+func TestRESPThrottle(t *testing.T) {
+	s, c := newRESPTest(t)
+	defer s.Close()
+
+	if reply, err := c.do("PING"); err != nil || reply != "PONG" {
+		t.Fatalf("expected PONG but got %v, %v", reply, err)
+	}
+
+	// 2 max burst (3 in total), 3 per 60 seconds.
+	tests := []struct {
+		quantity string
+		expected []interface{}
+	}{
+		{"1", throttleReply(0, 3, 2, -1, 60)},
+		{"2", throttleReply(0, 3, 0, -1, 60)},
+		{"1", throttleReply(1, 3, 0, 60, 60)},
+		{"4", throttleReply(1, 3, 0, -1, 60)},
+	}
+
+	for i, tt := range tests {
+		reply, err := c.do("CL.THROTTLE", "user123", "2", "3", "60", tt.quantity)
+		if err != nil {
+			t.Fatalf("[%d] %v", i, err)
+		}
+
+		if !reflect.DeepEqual(tt.expected, reply) {
+			t.Fatalf("[%d] expected %v but got %v", i, tt.expected, reply)
+		}
+	}
+
+	if _, ok := s.Limiter("user123"); ok {
+		t.Fatalf("expected the throttle key to be kept apart from the configured limiters")
+	}
+
+	// the limit follows the arguments of the last call.
+	reply, err := c.do("CL.THROTTLE", "user123", "4", "5", "60", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reply.([]interface{})[1]; got != int64(5) {
+		t.Fatalf("expected limit 5 but got %v", got)
+	}
+
+	// a configured limiter ignores the arguments.
+	reply, err = c.do("cl.throttle", "configured", "100", "100", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := throttleReply(0, 1, 0, -1, 60); !reflect.DeepEqual(expected, reply) {
+		t.Fatalf("expected %v but got %v", expected, reply)
+	}
+}
+
+func TestRESPThrottleEviction(t *testing.T) {
+	s, c := newRESPTest(t)
+	defer s.Close()
+	s.MaxThrottleKeys = 1
+
+	if _, err := c.do("CL.THROTTLE", "a", "0", "1", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.do("CL.THROTTLE", "b", "0", "1", "1"); err == nil || !strings.Contains(err.Error(), "too many") {
+		t.Fatalf("expected too many keys error but got %v", err)
+	}
+
+	// idle for a full circle.
+	s.throttleMu.Lock()
+	s.throttles["a"].used -= int64(2 * time.Second)
+	s.throttleSwept = 0
+	s.throttleMu.Unlock()
+
+	if _, err := c.do("CL.THROTTLE", "b", "0", "1", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.throttleMu.Lock()
+	_, ok := s.throttles["a"]
+	n := len(s.throttles)
+	s.throttleMu.Unlock()
+	if ok || n != 1 {
+		t.Fatalf("expected the idle key to be evicted but got %d keys", n)
+	}
+}
+
+func TestRESPLineTooLong(t *testing.T) {
+	s, c := newRESPTest(t)
+	defer s.Close()
+
+	go c.conn.Write(bytes.Repeat([]byte("a"), maxRESPLine+1))
+	if _, err := c.read(); err == nil || !strings.Contains(err.Error(), "Protocol error") {
+		t.Fatalf("expected protocol error but got %v", err)
+	}
+}
+
+func TestRESPErrors(t *testing.T) {
+	s, c := newRESPTest(t)
+	defer s.Close()
+
+	if _, err := c.do("CL.THROTTLE", "key", "1"); err == nil || !strings.Contains(err.Error(), "wrong number of arguments") {
+		t.Fatalf("expected wrong number of arguments error but got %v", err)
+	}
+
+	if _, err := c.do("CL.THROTTLE", "key", "1", "-1", "60"); err == nil {
+		t.Fatalf("expected an error for negative count")
+	}
+
+	if _, err := c.do("GET", "key"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Fatalf("expected unknown command error but got %v", err)
+	}
+
+	// inline commands are supported too.
+	if _, err := c.conn.Write([]byte("PING hello\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := c.read(); err != nil || reply != "hello" {
+		t.Fatalf("expected hello but got %v, %v", reply, err)
+	}
+
+	if reply, err := c.do("QUIT"); err != nil || reply != "OK" {
+		t.Fatalf("expected OK but got %v, %v", reply, err)
+	}
+}
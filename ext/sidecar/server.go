@@ -0,0 +1,255 @@
+// Package sidecar provides a server which hosts named limiters and exposes them
+// over HTTP/JSON, so services written in any language can share the same limits,
+// and a `Client` which implements the `chronos.Limiter` interface on top of it.
+//
+// The API, all requests are POST with a JSON body of {"name": "limiter name"}:
+//
+//	/v1/acquire responds when the operation is allowed: {"name": "..."},
+//	            or with an error when it's not, i.e the limiter is closed or paused.
+//	/v1/allow   responds immediately: {"name": "...", "allowed": true}
+//	/v1/reserve responds immediately with the wait in nanoseconds: {"name": "...", "wait": 0}
+//	/v1/stats   responds with the chronos.Stats of the limiter,
+//	            or of all limiters when the name is empty.
+//
+// The same limiters can be served through the Redis protocol too, see `Server#ServeRESP`.
+package sidecar
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+// Paths of the HTTP API.
+const (
+	PathAcquire = "/v1/acquire"
+	PathAllow   = "/v1/allow"
+	PathReserve = "/v1/reserve"
+	PathStats   = "/v1/stats"
+)
+
+type (
+	// Request is the body of all API requests.
+	Request struct {
+		Name string `json:"name"`
+	}
+
+	// Response is the body of the acquire, allow and reserve API responses.
+	Response struct {
+		Name    string        `json:"name"`
+		Allowed bool          `json:"allowed,omitempty"`
+		Wait    time.Duration `json:"wait,omitempty"`
+	}
+
+	// ErrorResponse is the body of a failed API request.
+	ErrorResponse struct {
+		Error string `json:"error"`
+	}
+)
+
+// Server hosts named limiters, it's an `http.Handler`.
+type Server struct {
+	mu       sync.RWMutex
+	limiters map[string]*chronos.C
+	mux      *http.ServeMux
+
+	// MaxThrottleKeys is the maximum keys of the CL.THROTTLE command which are not
+	// configured limiters, the next ones are rejected, see `ServeRESP`.
+	// Defaults to the `DefaultMaxThrottleKeys`.
+	MaxThrottleKeys int
+
+	// the RESP frontend, see `ServeRESP`.
+	throttleMu    sync.Mutex
+	throttles     map[string]*throttle
+	throttleSwept int64
+	respMu        sync.Mutex
+	respListeners map[net.Listener]struct{}
+	respConns     map[net.Conn]struct{}
+	closed        bool
+}
+
+// NewServer returns a new Server which hosts the limiters of the "cfg".
+func NewServer(cfg Config) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		limiters:      make(map[string]*chronos.C),
+		mux:           http.NewServeMux(),
+		throttles:     make(map[string]*throttle),
+		respListeners: make(map[net.Listener]struct{}),
+		respConns:     make(map[net.Conn]struct{}),
+	}
+
+	for name, l := range cfg.Limiters {
+		c := chronos.New(l.Max, time.Duration(l.Per))
+		c.SetDryRun(l.DryRun)
+		s.Handle(name, c)
+	}
+
+	s.mux.HandleFunc(PathAcquire, s.post(s.acquire))
+	s.mux.HandleFunc(PathAllow, s.post(s.allow))
+	s.mux.HandleFunc(PathReserve, s.post(s.reserve))
+	s.mux.HandleFunc(PathStats, s.post(s.stats))
+	return s, nil
+}
+
+// Handle registers, or replaces, the "c" limiter under the "name".
+func (s *Server) Handle(name string, c *chronos.C) {
+	s.mu.Lock()
+	s.limiters[name] = c
+	s.mu.Unlock()
+}
+
+// Limiter returns the limiter registered under the "name".
+func (s *Server) Limiter(name string) (*chronos.C, bool) {
+	s.mu.RLock()
+	c, ok := s.limiters[name]
+	s.mu.RUnlock()
+	return c, ok
+}
+
+// CloseLimiters closes the hosted limiters, the pending acquire requests
+// fail with 410 Gone, so an `http.Server#Shutdown` doesn't wait for them.
+func (s *Server) CloseLimiters() {
+	s.mu.RLock()
+	for _, c := range s.limiters {
+		c.Close()
+	}
+	s.mu.RUnlock()
+}
+
+// Names returns the sorted names of the hosted limiters.
+func (s *Server) Names() []string {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.limiters))
+	for name := range s.limiters {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP implements the `http.Handler` interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err string) {
+	writeJSON(w, statusCode, ErrorResponse{Error: err})
+}
+
+func (s *Server) post(handler func(http.ResponseWriter, *http.Request, Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+
+		handler(w, r, req)
+	}
+}
+
+func (s *Server) limiter(w http.ResponseWriter, name string) (*chronos.C, bool) {
+	c, ok := s.Limiter(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "limiter '"+name+"' not found")
+	}
+	return c, ok
+}
+
+// acquire waits until the operation is allowed, see `chronos.C#Wait`.
+// If the client goes away the operation is not counted.
+func (s *Server) acquire(w http.ResponseWriter, r *http.Request, req Request) {
+	c, ok := s.limiter(w, req.Name)
+	if !ok {
+		return
+	}
+
+	if err := c.Wait(r.Context()); err != nil {
+		if r.Context().Err() != nil {
+			return // the client went away.
+		}
+
+		writeError(w, statusCode(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Response{Name: req.Name, Allowed: true})
+}
+
+// statusCode returns the status code of a `chronos.C#Wait` error:
+// 409 Conflict when the limiter is paused or the operation can't fit in it,
+// 410 Gone when it's closed
+// and 503 Service Unavailable when it's draining or its queue is full.
+func statusCode(err error) int {
+	switch err {
+	case chronos.ErrPaused, chronos.ErrCostExceedsLimit:
+		return http.StatusConflict
+	case chronos.ErrClosed:
+		return http.StatusGone
+	default:
+		return http.StatusServiceUnavailable
+	}
+}
+
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, req Request) {
+	c, ok := s.limiter(w, req.Name)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Response{Name: req.Name, Allowed: c.Allow()})
+}
+
+func (s *Server) reserve(w http.ResponseWriter, r *http.Request, req Request) {
+	c, ok := s.limiter(w, req.Name)
+	if !ok {
+		return
+	}
+
+	wait := c.Reserve()
+	writeJSON(w, http.StatusOK, Response{Name: req.Name, Allowed: wait == 0, Wait: wait})
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request, req Request) {
+	if req.Name == "" {
+		all := make(map[string]chronos.Stats)
+		for _, name := range s.Names() {
+			if c, ok := s.Limiter(name); ok {
+				all[name] = c.Stats()
+			}
+		}
+
+		writeJSON(w, http.StatusOK, all)
+		return
+	}
+
+	c, ok := s.limiter(w, req.Name)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, c.Stats())
+}
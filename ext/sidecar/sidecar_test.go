@@ -0,0 +1,248 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	s, err := NewServer(Config{
+		Limiters: map[string]LimiterConfig{
+			"test": {Max: 2, Per: Duration(500 * time.Millisecond)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, httptest.NewServer(s)
+}
+
+func TestClient(t *testing.T) {
+	_, ts := newTestServer(t)
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "test")
+
+	if !c.Allow() {
+		t.Fatalf("expected first operation to be allowed")
+	}
+
+	if wait := c.Reserve(); wait != 0 {
+		t.Fatalf("expected second operation to be reserved without wait but got %s", wait)
+	}
+
+	if c.Allow() {
+		t.Fatalf("expected third operation to not be allowed")
+	}
+
+	st := c.Stats()
+	if st.Max != 2 || st.Length != 2 || st.Remaining != 0 {
+		t.Fatalf("unexpected stats: %#+v", st)
+	}
+
+	if wait := c.Reserve(); wait <= 0 || wait > 500*time.Millisecond {
+		t.Fatalf("expected reserve to wait for the current circle but got %s", wait)
+	}
+
+	// the reserved one is counted on the next circle, so this one has to wait too.
+	now := time.Now()
+	<-c.Acquire()
+	if since := time.Since(now); since < 400*time.Millisecond {
+		t.Fatalf("expected acquire to wait for the next circle but fired after %s", since)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	_, ts := newTestServer(t)
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "missing")
+	if _, err := c.AllowContext(context.Background()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a not found error but got %v", err)
+	}
+
+	// a 4xx is not retried.
+	select {
+	case _, ok := <-c.Acquire():
+		if ok {
+			t.Fatalf("expected the acquire of a missing limiter to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the acquire of a missing limiter to not be retried")
+	}
+
+	resp, err := http.Get(ts.URL + PathStats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if expected, got := http.StatusMethodNotAllowed, resp.StatusCode; expected != got {
+		t.Fatalf("expected status code %d but got %d", expected, got)
+	}
+}
+
+func TestAcquireCanceled(t *testing.T) {
+	s, ts := newTestServer(t)
+	defer ts.Close()
+
+	c, _ := s.Limiter("test")
+	c.Allow()
+	c.Allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := NewClient(ts.URL, "test").AcquireContext(ctx); err == nil {
+		t.Fatalf("expected the acquire to be canceled")
+	}
+}
+
+func TestAcquireNotAllowed(t *testing.T) {
+	s, ts := newTestServer(t)
+	defer ts.Close()
+
+	c, _ := s.Limiter("test")
+
+	acquire := func() int {
+		resp, err := http.Post(ts.URL+PathAcquire, "application/json", strings.NewReader(`{"name":"test"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// paused, in the reject mode.
+	c.PauseMode = chronos.PauseReject
+	c.Pause()
+	if expected, got := http.StatusConflict, acquire(); expected != got {
+		t.Fatalf("paused: expected status code %d but got %d", expected, got)
+	}
+
+	// draining.
+	c.Resume()
+	c.Allow()
+	c.Allow()
+	done := make(chan error)
+	go func() {
+		<-c.Acquire() // waits for the next circle, so the drain waits for it.
+		done <- nil
+	}()
+	for c.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() { done <- c.Drain(context.Background()) }()
+	for !c.IsDraining() {
+		time.Sleep(time.Millisecond)
+	}
+	if expected, got := http.StatusServiceUnavailable, acquire(); expected != got {
+		t.Fatalf("draining: expected status code %d but got %d", expected, got)
+	}
+	<-done
+	<-done
+
+	// closed.
+	c.Close()
+	if expected, got := http.StatusGone, acquire(); expected != got {
+		t.Fatalf("closed: expected status code %d but got %d", expected, got)
+	}
+
+	if st := c.Stats(); st.Acquired != 3 {
+		t.Fatalf("expected only the operations before the pause to be counted but got %d", st.Acquired)
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	s, ts := newTestServer(t)
+	c, _ := s.Limiter("test")
+	c.Close()
+
+	client := NewClient(ts.URL, "test")
+	select {
+	case _, ok := <-client.Acquire():
+		if ok {
+			t.Fatalf("expected the acquire of a closed limiter to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the acquire of a closed limiter to not be retried")
+	}
+
+	// the server is down, nothing is counted.
+	ts.Close()
+	if wait := client.Reserve(); wait != chronos.Never {
+		t.Fatalf("expected Never on error but got %s", wait)
+	}
+}
+
+func TestCloseLimiters(t *testing.T) {
+	s, ts := newTestServer(t)
+	defer ts.Close()
+
+	c, _ := s.Limiter("test")
+	c.Allow()
+	c.Allow()
+
+	done := make(chan error)
+	go func() { done <- NewClient(ts.URL, "test").AcquireContext(context.Background()) }()
+	for c.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	s.CloseLimiters()
+	var statusErr *StatusError
+	if err := <-done; !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusGone {
+		t.Fatalf("expected the pending acquire to fail with 410 but got %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chronosd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "chronosd.json")
+	data := `{"addr": ":9090", "limiters": {"a": {"max": 10, "per": "1m"}, "b": {"max": 1, "per": 1000000000, "dryRun": true}}}`
+	if err = ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Addr != ":9090" || cfg.Limiters["a"].Per != Duration(time.Minute) || cfg.Limiters["b"].Per != Duration(time.Second) ||
+		cfg.Limiters["a"].DryRun || !cfg.Limiters["b"].DryRun {
+		t.Fatalf("unexpected configuration: %#+v", cfg)
+	}
+
+	s, err := NewServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b, _ := s.Limiter("b"); !b.IsDryRun() || !b.Allow() || !b.Allow() {
+		t.Fatalf("expected the dry-run limiter to allow all operations")
+	}
+
+	data = `{"limiters": {"a": {"max": 0, "per": "1m"}}}`
+	if err = ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = LoadConfig(filename); err == nil {
+		t.Fatalf("expected an error for zero max")
+	}
+}
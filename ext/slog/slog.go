@@ -0,0 +1,214 @@
+// Package slog turns the decisions of chronos limiters into `log/slog` records.
+//
+// Example Code:
+//
+//	c := chronos.New(150, time.Minute)
+//	c.Observe(slog.New(logger, "ip-api"))
+//
+// Each record has the same attributes:
+//
+//	limiter   the limiter's name.
+//	key       the limiter's key, if any.
+//	decision  one of: acquire, wait, reject, circle, close, dryrun.
+//	cost      the number of the operations.
+//	wait      the time that the operations waited or will wait.
+//	remaining the operations that are allowed before the limiter starts to wait.
+//
+// Hot limiters can be sampled, see `Sampling`.
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+// The decisions.
+const (
+	DecisionAcquire = "acquire"
+	DecisionWait    = "wait"
+	DecisionReject  = "reject"
+	DecisionCircle  = "circle"
+	DecisionClose   = "close"
+	// DecisionDryRun is an operation which exceeded the limit
+	// but it was allowed by the dry-run mode, see `chronos.C#SetDryRun`.
+	DecisionDryRun = "dryrun"
+)
+
+// Sampling keeps the logs of hot limiters under control.
+// In every `Tick` duration, the `First` records of each decision are logged
+// and after that only every `Thereafter` record.
+type Sampling struct {
+	Tick       time.Duration
+	First      uint64
+	Thereafter uint64 // zero means none after the first ones.
+}
+
+type sampler struct {
+	Sampling
+	reset  int64 // unix nanoseconds, the start of the current tick.
+	counts [len(decisions)]uint64
+}
+
+func (s *sampler) allow(decision decision) bool {
+	now := time.Now().UnixNano()
+	if reset := atomic.LoadInt64(&s.reset); now-reset >= int64(s.Tick) {
+		if atomic.CompareAndSwapInt64(&s.reset, reset, now) {
+			for i := range s.counts {
+				atomic.StoreUint64(&s.counts[i], 0)
+			}
+		}
+	}
+
+	n := atomic.AddUint64(&s.counts[decision], 1)
+	if n <= s.First {
+		return true
+	}
+
+	return s.Thereafter > 0 && (n-s.First)%s.Thereafter == 0
+}
+
+// Observer is a `chronos.Observer` which logs the decisions of a limiter.
+// It can be notified by the ext/http.Client about its requests too.
+type Observer struct {
+	Logger  *slog.Logger
+	Limiter string
+	Key     string
+	// MinWait is the minimum wait of a request to be logged, see `OnRequestWait`.
+	MinWait time.Duration
+
+	sampler *sampler
+}
+
+var (
+	_ chronos.Observer       = (*Observer)(nil)
+	_ chronos.DryRunObserver = (*Observer)(nil)
+	_ chronos.WaitObserver   = (*Observer)(nil)
+)
+
+// New returns a new Observer which logs to the "logger"
+// the decisions of the limiter of the "limiter" name.
+// A nil "logger" means the `slog.Default()`.
+func New(logger *slog.Logger, limiter string) *Observer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Observer{
+		Logger:  logger,
+		Limiter: limiter,
+		MinWait: time.Millisecond,
+	}
+}
+
+// WithKey sets the key attribute of the records and returns the same Observer.
+func (o *Observer) WithKey(key string) *Observer {
+	o.Key = key
+	return o
+}
+
+// WithSampling enables the "sampling" and returns the same Observer.
+func (o *Observer) WithSampling(sampling Sampling) *Observer {
+	o.sampler = &sampler{Sampling: sampling}
+	return o
+}
+
+type decision int
+
+const (
+	acquireDecision decision = iota
+	waitDecision
+	rejectDecision
+	circleDecision
+	closeDecision
+	dryRunDecision
+)
+
+var decisions = [...]string{DecisionAcquire, DecisionWait, DecisionReject, DecisionCircle, DecisionClose, DecisionDryRun}
+
+func (o *Observer) log(level slog.Level, decision decision, c *chronos.C, cost uint32, wait time.Duration, attrs ...slog.Attr) {
+	ctx := context.Background()
+	if !o.Logger.Enabled(ctx, level) {
+		return
+	}
+
+	if o.sampler != nil && !o.sampler.allow(decision) {
+		return
+	}
+
+	attrs = append(attrs,
+		slog.String("limiter", o.Limiter),
+		slog.String("key", o.Key),
+		slog.String("decision", decisions[decision]),
+		slog.Uint64("cost", uint64(cost)),
+		slog.Duration("wait", wait),
+		slog.Uint64("remaining", uint64(c.Remaining())),
+	)
+
+	o.Logger.LogAttrs(ctx, level, "chronos: "+decisions[decision], attrs...)
+}
+
+// OnAcquire implements the `chronos.Observer` interface, it logs at debug level.
+func (o *Observer) OnAcquire(c *chronos.C, n uint32, wait time.Duration) {
+	o.log(slog.LevelDebug, acquireDecision, c, n, wait)
+}
+
+// OnWait implements the `chronos.Observer` interface, it logs at info level.
+func (o *Observer) OnWait(c *chronos.C, delay time.Duration) {
+	o.OnWaitN(c, 1, delay)
+}
+
+// OnWaitN implements the `chronos.WaitObserver` interface, it logs at info level
+// with the cost of the scheduled operations.
+func (o *Observer) OnWaitN(c *chronos.C, n uint32, delay time.Duration) {
+	o.log(slog.LevelInfo, waitDecision, c, n, delay)
+}
+
+// OnReject implements the `chronos.Observer` interface, it logs at warn level.
+func (o *Observer) OnReject(c *chronos.C, n uint32) {
+	o.log(slog.LevelWarn, rejectDecision, c, n, 0)
+}
+
+// OnCircle implements the `chronos.Observer` interface, it logs at debug level.
+func (o *Observer) OnCircle(c *chronos.C, circle uint64) {
+	o.log(slog.LevelDebug, circleDecision, c, 0, 0, slog.Uint64("circle", circle))
+}
+
+// OnClose implements the `chronos.Observer` interface, it logs at info level.
+func (o *Observer) OnClose(c *chronos.C) {
+	o.log(slog.LevelInfo, closeDecision, c, 0, 0)
+}
+
+// OnDryRun implements the `chronos.DryRunObserver` interface, it logs at warn level,
+// as the `OnReject`, the "wait" attribute is the time that the operations would wait.
+func (o *Observer) OnDryRun(c *chronos.C, n uint32, retryAfter time.Duration) {
+	o.log(slog.LevelWarn, dryRunDecision, c, n, retryAfter)
+}
+
+// OnRequestWait logs, at info level, a request which waited
+// for the limiter at least `MinWait`, with its method and URL.
+// It implements the ext/http.RequestObserver interface.
+func (o *Observer) OnRequestWait(req *http.Request, wait time.Duration) {
+	if wait < o.MinWait {
+		return
+	}
+
+	ctx := req.Context()
+	if !o.Logger.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+
+	o.Logger.LogAttrs(ctx, slog.LevelInfo, "chronos: request waited",
+		slog.String("limiter", o.Limiter),
+		slog.String("key", o.Key),
+		slog.String("decision", DecisionWait),
+		slog.Uint64("cost", 1),
+		slog.Duration("wait", wait),
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+	)
+}
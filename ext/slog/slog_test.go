@@ -0,0 +1,141 @@
+package slog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kataras/chronos"
+	chronoshttp "github.com/kataras/chronos/ext/http"
+)
+
+func newTestLogger() (*slog.Logger, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	return slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})), buf
+}
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var recs []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		rec := make(map[string]interface{})
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func TestObserver(t *testing.T) {
+	logger, buf := newTestLogger()
+
+	c := chronos.New(1, time.Hour)
+	c.Observe(New(logger, "api").WithKey("john"))
+
+	c.Allow()
+	c.Allow()
+	c.Close()
+
+	recs := records(t, buf)
+	if len(recs) != 3 {
+		t.Fatalf("expected 3 records but got %d:\n%s", len(recs), buf)
+	}
+
+	expected := []struct {
+		level     string
+		decision  string
+		remaining float64
+	}{
+		{"DEBUG", DecisionAcquire, 0},
+		{"WARN", DecisionReject, 0},
+		{"INFO", DecisionClose, 0},
+	}
+
+	for i, tt := range expected {
+		rec := recs[i]
+		if rec["level"] != tt.level || rec["decision"] != tt.decision || rec["remaining"] != tt.remaining ||
+			rec["limiter"] != "api" || rec["key"] != "john" || rec["cost"] == nil || rec["wait"] == nil {
+			t.Fatalf("[%d] unexpected record: %v", i, rec)
+		}
+	}
+}
+
+func TestWaitCost(t *testing.T) {
+	logger, buf := newTestLogger()
+
+	c := chronos.New(2, 100*time.Millisecond)
+	c.Observe(New(logger, "api"))
+
+	c.AllowN(2)
+	if err := c.WaitN(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rec := range records(t, buf) {
+		if rec["decision"] == DecisionWait {
+			if rec["cost"] != float64(2) {
+				t.Fatalf("expected the wait record to have cost 2 but got: %v", rec)
+			}
+			return
+		}
+	}
+	t.Fatalf("no wait record:\n%s", buf)
+}
+
+func TestSampling(t *testing.T) {
+	logger, buf := newTestLogger()
+
+	c := chronos.New(1, time.Hour)
+	c.Allow()
+	c.Observe(New(logger, "hot").WithSampling(Sampling{Tick: time.Hour, First: 2, Thereafter: 3}))
+
+	for i := 0; i < 10; i++ {
+		c.Allow()
+	}
+
+	// 1, 2 and then 5, 8.
+	if expected, got := 4, len(records(t, buf)); expected != got {
+		t.Fatalf("expected %d sampled records but got %d", expected, got)
+	}
+}
+
+func TestRequestWait(t *testing.T) {
+	logger, buf := newTestLogger()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	per := 200 * time.Millisecond
+	client := chronoshttp.New(1, per)
+	client.Observer = New(logger, "srv")
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL + "/path")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	recs := records(t, buf)
+	if len(recs) != 1 {
+		t.Fatalf("expected only the request that waited to be logged but got:\n%s", buf)
+	}
+
+	rec := recs[0]
+	if rec["url"] != srv.URL+"/path" || rec["method"] != "GET" || rec["limiter"] != "srv" || !strings.Contains(rec["msg"].(string), "request waited") {
+		t.Fatalf("unexpected record: %v", rec)
+	}
+
+	if wait := time.Duration(rec["wait"].(float64)); wait < per/2 {
+		t.Fatalf("expected the logged wait to be about %s but got %s", per, wait)
+	}
+}
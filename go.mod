@@ -0,0 +1,3 @@
+module github.com/kataras/chronos
+
+go 1.21
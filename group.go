@@ -0,0 +1,301 @@
+package chronos
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Group is a limiter made of other limiters, an operation is allowed
+// only if all of them allow it and then it's counted by all of them.
+// It's useful to apply more than one limit to the same operations,
+// i.e a daily quota alongside a per second limit:
+//
+//	g := chronos.All(chronos.NewWindow(10000, chronos.Daily(time.UTC)), chronos.New(10, time.Second))
+//	<-g.Acquire()
+//
+// The limiters can still be used on their own, or by other groups, at the same time.
+// Note that the `Pause` of a limiter is respected by the group but the waiting operations
+// of the group are not held by the limiter, they retry until the limiter is resumed.
+type Group struct {
+	limiters []*C
+	locks    []*C // the unique limiters, sorted by their lock order.
+}
+
+var _ Limiter = (*Group)(nil)
+
+var lastLockID uint64
+
+// getLockID returns the order that the limiter is locked by the groups,
+// so two groups that share limiters can't deadlock.
+func (c *C) getLockID() uint64 {
+	if id := atomic.LoadUint64(&c.lockID); id != 0 {
+		return id
+	}
+
+	atomic.CompareAndSwapUint64(&c.lockID, 0, atomic.AddUint64(&lastLockID, 1))
+	return atomic.LoadUint64(&c.lockID)
+}
+
+// All returns a new Group of the "limiters".
+func All(limiters ...*C) *Group {
+	g := &Group{limiters: limiters}
+
+	seen := make(map[*C]struct{}, len(limiters))
+	for _, c := range limiters {
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = struct{}{}
+		g.locks = append(g.locks, c)
+	}
+
+	sort.Slice(g.locks, func(i, j int) bool { return g.locks[i].getLockID() < g.locks[j].getLockID() })
+	return g
+}
+
+// Limiters returns the limiters of the group.
+func (g *Group) Limiters() []*C {
+	return g.limiters
+}
+
+func (g *Group) lock() {
+	for _, c := range g.locks {
+		c.mu.Lock()
+	}
+}
+
+func (g *Group) unlock() {
+	for i := len(g.locks) - 1; i >= 0; i-- {
+		g.locks[i].mu.Unlock()
+	}
+}
+
+// groupDecision is the decision of a limiter of the group,
+// the limiter is notified after the group is unlocked.
+type groupDecision struct {
+	sched  int64
+	ok     bool
+	circle uint64
+}
+
+// take counts "n" operations to all limiters if all of them allow them,
+// otherwise it returns the greatest duration that should be passed before retry,
+// or the reason that they are not admitted.
+// The "canWait" reports whether the caller waits if they are not allowed,
+// the "waited" whether it already waited since the "start".
+func (g *Group) take(n uint32, start int64, canWait, waited bool) (sched int64, limited LimitError, err error) {
+	now := time.Now().UnixNano()
+	decisions := make([]groupDecision, len(g.locks))
+	cause := -1
+
+	g.lock()
+	for i, c := range g.locks {
+		switch {
+		case c.IsClosed():
+			err = ErrClosed
+		case c.draining:
+			err = ErrDraining
+		case c.paused && (c.PauseMode == PauseReject || !canWait):
+			err = ErrPaused
+		case c.paused:
+			// retry until it's resumed.
+			decisions[i].sched = int64(drainInterval)
+		case n > c.Max && !c.dryRun:
+			err = ErrCostExceedsLimit
+		default:
+			c.ask(now)
+			s, ok, _ := c.check(now, n)
+			if !ok && !c.dryRun {
+				decisions[i].sched = s
+				if s > sched {
+					limited = LimitError{Limit: c.limit(now), Per: c.period(now), RetryAfter: time.Duration(s)}
+					if length := c.getCurrentLength(); length < limited.Limit {
+						limited.Remaining = limited.Limit - length
+					}
+				}
+			}
+		}
+
+		if err != nil {
+			cause = i
+			break
+		}
+
+		if decisions[i].sched > sched {
+			sched = decisions[i].sched
+		}
+	}
+
+	if sched == 0 && err == nil {
+		for i, c := range g.locks {
+			decisions[i].sched, decisions[i].ok, decisions[i].circle = c.take(now, n)
+		}
+	}
+	g.unlock()
+
+	if err != nil {
+		if err != ErrClosed {
+			g.locks[cause].reject(n)
+		}
+		return 0, limited, err
+	}
+
+	if sched > 0 {
+		if !canWait {
+			for i, c := range g.locks {
+				if decisions[i].sched > 0 {
+					c.reject(n)
+				}
+			}
+		}
+		return sched, limited, nil
+	}
+
+	wait := time.Duration(now - start)
+	for i, c := range g.locks {
+		d := decisions[i]
+		if d.circle != 0 {
+			c.notifyCircle(d.circle)
+		}
+
+		if d.sched > 0 {
+			c.notifyDryRun(n, time.Duration(d.sched))
+		}
+
+		atomic.AddUint64(&c.acquired, uint64(n))
+		if waited {
+			atomic.AddUint64(&c.waited, uint64(n))
+		}
+		c.waitHistogram().observe(wait)
+		c.notifyAcquire(n, wait)
+	}
+
+	return 0, limited, nil
+}
+
+func (g *Group) setWaiting(delta int64) {
+	for _, c := range g.locks {
+		atomic.AddInt64(&c.waiting, delta)
+	}
+}
+
+// Wait blocks until an operation is allowed by all limiters or the "ctx" is done,
+// see `C#Wait`. The `C.QueueSize` of the limiters is not respected.
+func (g *Group) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	start := time.Now().UnixNano()
+	sched, _, err := g.take(1, start, true, false)
+	if err != nil || sched == 0 {
+		return err
+	}
+
+	g.setWaiting(1)
+	defer g.setWaiting(-1)
+
+	timer := time.NewTimer(time.Duration(sched))
+	defer timer.Stop()
+
+	for {
+		for _, c := range g.locks {
+			c.notifyWait(1, time.Duration(sched))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		sched, _, err = g.take(1, start, true, true)
+		if err != nil || sched == 0 {
+			return err
+		}
+		timer.Reset(time.Duration(sched))
+	}
+}
+
+// Acquire blocks until the operation is allowed by all limiters.
+// The returned channel is closed without a value if the operation
+// can't be allowed, i.e a limiter is closed.
+func (g *Group) Acquire() <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	go func() {
+		if err := g.Wait(context.Background()); err != nil {
+			close(ch)
+			return
+		}
+		ch <- emptyStruct
+	}()
+	return ch
+}
+
+// Allow reports whether an operation is allowed now by all limiters.
+func (g *Group) Allow() bool {
+	return g.AllowN(1)
+}
+
+// AllowN is like `Allow` but for "n" operations at once.
+func (g *Group) AllowN(n uint32) bool {
+	sched, _, err := g.take(n, time.Now().UnixNano(), false, false)
+	return err == nil && sched == 0
+}
+
+// Take is like `Allow` but it returns an error instead, see `TakeN`.
+func (g *Group) Take() error {
+	return g.TakeN(1)
+}
+
+// TakeN is like `C#TakeN`, the `*LimitError` is
+// the one of the limiter which should be waited the most.
+func (g *Group) TakeN(n uint32) error {
+	sched, limited, err := g.take(n, time.Now().UnixNano(), false, false)
+	if err != nil {
+		return err
+	}
+	if sched > 0 {
+		return &limited
+	}
+	return nil
+}
+
+// Reserve counts an operation and returns the duration that the caller
+// should wait before execute it, see `C#Reserve`.
+func (g *Group) Reserve() time.Duration {
+	start := time.Now().UnixNano()
+	sched, _, err := g.take(1, start, true, false)
+	if err != nil {
+		return Never
+	}
+
+	if sched > 0 {
+		go func() {
+			g.setWaiting(1)
+			defer g.setWaiting(-1)
+
+			for sched > 0 && err == nil {
+				time.Sleep(time.Duration(sched))
+				sched, _, err = g.take(1, start, true, true)
+			}
+		}()
+	}
+
+	return time.Duration(sched)
+}
+
+// Stats returns the stats of the most restrictive limiter,
+// the one with the fewest remaining operations.
+func (g *Group) Stats() Stats {
+	var st Stats
+	for i, c := range g.limiters {
+		s := c.Stats()
+		if i == 0 || s.Remaining < st.Remaining || (s.Remaining == st.Remaining && s.ResetAfter > st.ResetAfter) {
+			st = s
+		}
+	}
+	return st
+}
@@ -0,0 +1,89 @@
+package chronos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+	quota := New(3, time.Hour)
+	perSecond := New(2, 300*time.Millisecond)
+	g := All(quota, perSecond)
+
+	if !g.Allow() || !g.Allow() {
+		t.Fatalf("expected the first two operations to be allowed")
+	}
+
+	// the per second limit is reached, the quota should not count it.
+	err := g.Take()
+	var limited *LimitError
+	if !errors.As(err, &limited) || limited.Limit != 2 || limited.RetryAfter <= 0 {
+		t.Fatalf("expected the *LimitError of the per second limiter but got %v", err)
+	}
+
+	if st := quota.Stats(); st.Length != 2 || st.Rejected != 0 {
+		t.Fatalf("expected the quota to not count the rejected operation: %#+v", st)
+	}
+
+	if st := perSecond.Stats(); st.Length != 2 || st.Rejected != 1 {
+		t.Fatalf("expected the per second limiter to count the rejected operation: %#+v", st)
+	}
+
+	start := time.Now()
+	if err = g.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("expected to wait for the per second limiter but waited %s", elapsed)
+	}
+
+	// the quota is reached, it's not allowed even on a new second.
+	time.Sleep(300 * time.Millisecond)
+	if err = g.Take(); !errors.As(err, &limited) || limited.Limit != 3 {
+		t.Fatalf("expected the *LimitError of the quota but got %v", err)
+	}
+
+	if st := g.Stats(); st.Max != 3 || st.Remaining != 0 {
+		t.Fatalf("expected the stats of the quota but got %#+v", st)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err = g.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the wait to be canceled but got %v", err)
+	}
+
+	if st := quota.Stats(); st.Acquired != 3 || st.Waited != 1 || st.Waiting != 0 {
+		t.Fatalf("unexpected quota stats: %#+v", st)
+	}
+
+	perSecond.Close()
+	if _, ok := <-g.Acquire(); ok {
+		t.Fatalf("expected acquire to be released with a closed channel")
+	}
+}
+
+func TestGroupSharedLimiters(t *testing.T) {
+	a, b := New(1000, time.Hour), New(1000, time.Hour)
+	// the same limiters in a different order, they should not deadlock.
+	g1, g2 := All(a, b, a), All(b, a)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 200; i++ {
+			g1.Allow()
+		}
+		close(done)
+	}()
+
+	for i := 0; i < 200; i++ {
+		g2.Allow()
+	}
+	<-done
+
+	if st := a.Stats(); st.Length != 400 {
+		t.Fatalf("expected 400 operations but got %d", st.Length)
+	}
+}
@@ -0,0 +1,197 @@
+package chronos
+
+import (
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWaitBuckets are the upper bounds of the buckets
+// that the wait durations of the `Acquire` are counted into.
+var DefaultWaitBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// ExponentialBuckets returns "count" buckets, the first one is the "start"
+// and each next one is "factor" times the previous one.
+func ExponentialBuckets(start time.Duration, factor float64, count int) []time.Duration {
+	buckets := make([]time.Duration, count)
+	bound := float64(start)
+	for i := range buckets {
+		buckets[i] = time.Duration(bound)
+		bound *= factor
+	}
+	return buckets
+}
+
+// LinearBuckets returns "count" buckets, the first one is the "start"
+// and each next one is "width" greater than the previous one.
+func LinearBuckets(start, width time.Duration, count int) []time.Duration {
+	buckets := make([]time.Duration, count)
+	for i := range buckets {
+		buckets[i] = start + time.Duration(i)*width
+	}
+	return buckets
+}
+
+// ErrBucketsMismatch is returned by `Histogram#Merge` when
+// the histograms have different buckets.
+var ErrBucketsMismatch = errors.New("chronos: histogram buckets mismatch")
+
+// Histogram is a snapshot of the wait durations of a limiter.
+type Histogram struct {
+	// Buckets are the upper bounds, inclusive, of the buckets.
+	Buckets []time.Duration `json:"buckets"`
+	// Counts are the number of the durations of each bucket,
+	// it has one more element than the `Buckets`, the durations greater than the last bucket.
+	Counts []uint64      `json:"counts"`
+	Count  uint64        `json:"count"`
+	Sum    time.Duration `json:"sum"`
+	Max    time.Duration `json:"max"` // the greatest recorded duration.
+}
+
+// Mean returns the average duration.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Percentile returns an estimation of the "p" percentile, from 0 to 100,
+// i.e 99 for the p99. The durations are considered as evenly distributed
+// inside their bucket, so the accuracy depends on the buckets.
+func (h Histogram) Percentile(p float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	if p <= 0 {
+		p = 0
+	} else if p > 100 {
+		p = 100
+	}
+
+	rank := p / 100 * float64(h.Count)
+	var cumulative uint64
+	for i, count := range h.Counts {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+
+		var lower, upper time.Duration
+		if i > 0 {
+			lower = h.Buckets[i-1]
+		}
+
+		if i < len(h.Buckets) {
+			upper = h.Buckets[i]
+		} else {
+			// the overflow bucket, its upper bound is the greatest recorded one.
+			upper = h.Max
+		}
+
+		if upper > h.Max {
+			upper = h.Max
+		}
+		if lower > upper {
+			return upper
+		}
+
+		fraction := (rank - float64(cumulative)) / float64(count)
+		return lower + time.Duration(fraction*float64(upper-lower))
+	}
+
+	return h.Max
+}
+
+// Merge adds the "other" histogram to this one,
+// both should have the same buckets.
+func (h *Histogram) Merge(other Histogram) error {
+	if len(h.Buckets) == 0 && len(h.Counts) == 0 {
+		h.Buckets = append([]time.Duration(nil), other.Buckets...)
+		h.Counts = make([]uint64, len(other.Counts))
+	}
+
+	if len(h.Buckets) != len(other.Buckets) || len(h.Counts) != len(other.Counts) {
+		return ErrBucketsMismatch
+	}
+
+	for i := range h.Buckets {
+		if h.Buckets[i] != other.Buckets[i] {
+			return ErrBucketsMismatch
+		}
+	}
+
+	for i := range h.Counts {
+		h.Counts[i] += other.Counts[i]
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+	if other.Max > h.Max {
+		h.Max = other.Max
+	}
+
+	return nil
+}
+
+// histogram records durations, its methods are safe for concurrent use.
+type histogram struct {
+	buckets []time.Duration
+	counts  []uint64
+	count   uint64
+	sum     int64
+	max     int64
+}
+
+func newHistogram(buckets []time.Duration) *histogram {
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := sort.Search(len(h.buckets), func(i int) bool { return d <= h.buckets[i] })
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddInt64(&h.sum, int64(d))
+
+	for {
+		max := atomic.LoadInt64(&h.max)
+		if int64(d) <= max || atomic.CompareAndSwapInt64(&h.max, max, int64(d)) {
+			break
+		}
+	}
+}
+
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Buckets: append([]time.Duration(nil), h.buckets...),
+		Counts:  make([]uint64, len(h.counts)),
+		Count:   atomic.LoadUint64(&h.count),
+		Sum:     time.Duration(atomic.LoadInt64(&h.sum)),
+		Max:     time.Duration(atomic.LoadInt64(&h.max)),
+	}
+
+	for i := range h.counts {
+		s.Counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+
+	return s
+}
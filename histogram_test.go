@@ -0,0 +1,95 @@
+package chronos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	h := newHistogram(LinearBuckets(10*time.Millisecond, 10*time.Millisecond, 10)) // 10ms...100ms.
+	for i := 1; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	h.observe(time.Second)
+
+	s := h.snapshot()
+	if s.Count != 101 || s.Max != time.Second {
+		t.Fatalf("unexpected snapshot: %#+v", s)
+	}
+
+	tests := []struct {
+		p        float64
+		min, max time.Duration
+	}{
+		{0, 0, 10 * time.Millisecond},
+		{50, 45 * time.Millisecond, 55 * time.Millisecond},
+		{90, 85 * time.Millisecond, 95 * time.Millisecond},
+		{99, 95 * time.Millisecond, 100 * time.Millisecond},
+		{100, time.Second, time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := s.Percentile(tt.p); got < tt.min || got > tt.max {
+			t.Fatalf("expected p%v to be between %s and %s but got %s", tt.p, tt.min, tt.max, got)
+		}
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a := newHistogram(ExponentialBuckets(time.Millisecond, 10, 3)) // 1ms, 10ms, 100ms.
+	a.observe(time.Millisecond)
+	b := newHistogram(ExponentialBuckets(time.Millisecond, 10, 3))
+	b.observe(50 * time.Millisecond)
+	b.observe(time.Second)
+
+	var merged Histogram
+	if err := merged.Merge(a.snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	if err := merged.Merge(b.snapshot()); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []uint64{1, 0, 1, 1}; merged.Count != 3 || merged.Max != time.Second || len(merged.Counts) != len(expected) ||
+		merged.Counts[0] != expected[0] || merged.Counts[2] != expected[2] || merged.Counts[3] != expected[3] {
+		t.Fatalf("unexpected merged histogram: %#+v", merged)
+	}
+
+	if err := merged.Merge(newHistogram(DefaultWaitBuckets).snapshot()); err != ErrBucketsMismatch {
+		t.Fatalf("expected %v but got %v", ErrBucketsMismatch, err)
+	}
+
+	// the snapshots and the merged histogram don't share their buckets.
+	snapshot := a.snapshot()
+	snapshot.Buckets[0] = time.Hour
+	merged.Buckets[1] = time.Hour
+	if a.buckets[0] != time.Millisecond || b.buckets[1] != 10*time.Millisecond {
+		t.Fatalf("expected the buckets of the histograms to be left untouched but got %v and %v", a.buckets, b.buckets)
+	}
+}
+
+func TestWaitHistogram(t *testing.T) {
+	per := 100 * time.Millisecond
+	c := New(1, per)
+	c.WaitBuckets = []time.Duration{50 * time.Millisecond, time.Second}
+
+	<-c.Acquire()
+	<-c.Acquire()
+
+	wait := c.Stats().Wait
+	if expected := []uint64{1, 1, 0}; wait.Count != 2 || wait.Counts[0] != expected[0] || wait.Counts[1] != expected[1] {
+		t.Fatalf("unexpected wait histogram: %#+v", wait)
+	}
+
+	if p := wait.Percentile(100); p < per/2 {
+		t.Fatalf("expected p100 to be about %s but got %s", per, p)
+	}
+
+	if old := c.ResetWaits(); old.Count != 2 {
+		t.Fatalf("expected the histogram before the reset to be returned")
+	}
+
+	if wait = c.Stats().Wait; wait.Count != 0 {
+		t.Fatalf("expected an empty histogram after reset but got %#+v", wait)
+	}
+}
@@ -0,0 +1,202 @@
+package chronos
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// LatencyAlgorithm estimates a concurrency limit from the observed latencies,
+// see `LatencyLimiter`. Its methods are called under the limiter's lock.
+type LatencyAlgorithm interface {
+	// Update returns the new limit from the current "limit" and a sample,
+	// the "latency" of an operation, the operations that were in-flight with it
+	// and whether it's dropped, i.e timed out or rejected by the remote service.
+	Update(limit float64, latency time.Duration, inFlight uint32, dropped bool) float64
+}
+
+// Vegas is a `LatencyAlgorithm` like the TCP Vegas congestion control.
+// It estimates the queue of the remote service from the minimum latency, the latency without load,
+// and it increases the limit while the queue is small and decreases it when the queue grows.
+type Vegas struct {
+	// Alpha and Beta are the queue sizes, in log10 of the limit,
+	// which the limit increases below and decreases above, default to 3 and 6.
+	Alpha, Beta float64
+
+	noLoad time.Duration // the minimum latency.
+}
+
+var _ LatencyAlgorithm = (*Vegas)(nil)
+
+// log10 is the step of the Vegas limit, at least 1.
+func log10(limit float64) float64 {
+	return math.Max(1, math.Log10(limit))
+}
+
+// Update implements the `LatencyAlgorithm` interface.
+func (v *Vegas) Update(limit float64, latency time.Duration, inFlight uint32, dropped bool) float64 {
+	if latency <= 0 {
+		return limit
+	}
+
+	if v.noLoad == 0 || latency < v.noLoad {
+		v.noLoad = latency
+	}
+
+	if dropped {
+		return limit - log10(limit)
+	}
+
+	// the remote service is not limited by the limit.
+	if float64(inFlight)*2 < limit {
+		return limit
+	}
+
+	alpha, beta := v.Alpha, v.Beta
+	if alpha == 0 {
+		alpha = 3
+	}
+	if beta == 0 {
+		beta = 6
+	}
+
+	queue := math.Ceil(limit * (1 - float64(v.noLoad)/float64(latency)))
+	step := log10(limit)
+	switch {
+	case queue < alpha*step:
+		return limit + step
+	case queue > beta*step:
+		return limit - step
+	default:
+		return limit
+	}
+}
+
+// Gradient is a `LatencyAlgorithm` like the gradient algorithm of the Netflix's concurrency-limits.
+// The gradient is the ratio of the minimum latency, the latency without load, to the latency,
+// a latency which grows decreases the limit by the gradient and
+// the limit is increased by its square root, the queue which is allowed.
+type Gradient struct {
+	// Tolerance is the latency, as a factor of the minimum latency,
+	// which is tolerated before the limit decreases, defaults to 1.
+	Tolerance float64
+	// Smoothing is the weight of a new limit, defaults to 0.2.
+	Smoothing float64
+
+	noLoad time.Duration // the minimum latency.
+}
+
+var _ LatencyAlgorithm = (*Gradient)(nil)
+
+// Update implements the `LatencyAlgorithm` interface.
+func (g *Gradient) Update(limit float64, latency time.Duration, inFlight uint32, dropped bool) float64 {
+	if latency <= 0 {
+		return limit
+	}
+
+	if g.noLoad == 0 || latency < g.noLoad {
+		g.noLoad = latency
+	}
+
+	// the remote service is not limited by the limit.
+	if !dropped && float64(inFlight)*2 < limit {
+		return limit
+	}
+
+	tolerance := g.Tolerance
+	if tolerance <= 0 {
+		tolerance = 1
+	}
+	smoothing := g.Smoothing
+	if smoothing <= 0 {
+		smoothing = 0.2
+	}
+
+	gradient := math.Max(0.5, math.Min(1, tolerance*float64(g.noLoad)/float64(latency)))
+	if dropped {
+		gradient = 0.5
+	}
+
+	next := limit*gradient + math.Sqrt(limit)
+	return limit*(1-smoothing) + next*smoothing
+}
+
+// LatencyLimiter is a `Concurrency` limiter which tunes its in-flight limit
+// from the latencies that are reported to it, through a `LatencyAlgorithm`,
+// i.e `Vegas` or `Gradient`, instead of a fixed limit.
+// The limit is kept between the `Floor` and the `Ceiling`.
+//
+// Example Code:
+//
+//	l := chronos.NewLatencyLimiter(10, 1, 200, new(chronos.Gradient))
+//	release, err := l.Acquire(ctx)
+//	if err != nil { ... }
+//	start := time.Now()
+//	err = call()
+//	l.Report(time.Since(start), isTimeout(err))
+//	release()
+type LatencyLimiter struct {
+	*Concurrency
+
+	Floor   uint32
+	Ceiling uint32
+
+	mu        sync.Mutex
+	algorithm LatencyAlgorithm
+	estimate  float64
+}
+
+// NewLatencyLimiter returns a new limiter which starts with "initial" in-flight operations
+// and tunes its limit between the "floor" and the "ceiling" through the "algorithm".
+func NewLatencyLimiter(initial, floor, ceiling uint32, algorithm LatencyAlgorithm) *LatencyLimiter {
+	l := &LatencyLimiter{
+		Floor:     floor,
+		Ceiling:   ceiling,
+		algorithm: algorithm,
+		estimate:  float64(initial),
+	}
+	l.estimate = l.bound(l.estimate)
+	l.Concurrency = NewConcurrency(uint32(l.estimate))
+	return l
+}
+
+func (l *LatencyLimiter) bound(limit float64) float64 {
+	if limit < float64(l.Floor) {
+		limit = float64(l.Floor)
+	}
+	if l.Ceiling > 0 && limit > float64(l.Ceiling) {
+		limit = float64(l.Ceiling)
+	}
+	return math.Max(1, limit)
+}
+
+// Report reports the "latency" of an operation, and whether it's dropped,
+// i.e timed out or rejected by the remote service, the limit is tuned to it.
+// It should be called before the operation's slot is released.
+func (l *LatencyLimiter) Report(latency time.Duration, dropped bool) {
+	l.report(latency, dropped, l.Concurrency.Stats().InFlight)
+}
+
+func (l *LatencyLimiter) report(latency time.Duration, dropped bool, inFlight uint32) {
+	l.mu.Lock()
+	old := uint32(l.estimate)
+	l.estimate = l.bound(l.algorithm.Update(l.estimate, latency, inFlight, dropped))
+	// under lock, so the limits are set in order.
+	if limit := uint32(l.estimate); limit != old {
+		l.Concurrency.SetLimit(limit)
+	}
+	l.mu.Unlock()
+}
+
+// Estimate returns the current estimate of the limit, its fraction is kept between the reports.
+func (l *LatencyLimiter) Estimate() float64 {
+	l.mu.Lock()
+	estimate := l.estimate
+	l.mu.Unlock()
+	return estimate
+}
+
+// Limit returns the current in-flight limit.
+func (l *LatencyLimiter) Limit() uint32 {
+	return uint32(l.Estimate())
+}
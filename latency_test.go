@@ -0,0 +1,74 @@
+package chronos
+
+import (
+	"testing"
+	"time"
+)
+
+// simulate reports the latencies of a remote service which runs "capacity"
+// operations at the same time in "base" latency, the next ones are queued,
+// under a load which always fills the limit. It returns the last limit.
+func simulate(l *LatencyLimiter, capacity uint32, base time.Duration, samples int) uint32 {
+	for i := 0; i < samples; i++ {
+		inFlight := l.Limit()
+		latency := base
+		if inFlight > capacity {
+			latency = base * time.Duration(inFlight) / time.Duration(capacity)
+		}
+		l.report(latency, false, inFlight)
+	}
+	return l.Limit()
+}
+
+func TestLatencyLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm func() LatencyAlgorithm
+	}{
+		{"vegas", func() LatencyAlgorithm { return new(Vegas) }},
+		{"gradient", func() LatencyAlgorithm { return new(Gradient) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// from below, it grows to the capacity.
+			l := NewLatencyLimiter(5, 1, 1000, tt.algorithm())
+			if limit := simulate(l, 50, 10*time.Millisecond, 2000); limit < 40 || limit > 80 {
+				t.Fatalf("expected limit to converge to the capacity of 50 but got %d", limit)
+			}
+
+			// the capacity is halved, it follows.
+			if limit := simulate(l, 25, 10*time.Millisecond, 2000); limit < 20 || limit > 40 {
+				t.Fatalf("expected limit to converge to the capacity of 25 but got %d", limit)
+			}
+
+			if st := l.Stats(); st.Max != l.Limit() {
+				t.Fatalf("expected the in-flight limit to be the estimate %d but got %d", l.Limit(), st.Max)
+			}
+		})
+	}
+}
+
+func TestLatencyLimiterBounds(t *testing.T) {
+	l := NewLatencyLimiter(10, 5, 12, new(Vegas))
+	for i := 0; i < 100; i++ {
+		l.report(time.Millisecond, false, l.Limit())
+	}
+	if limit := l.Limit(); limit != 12 {
+		t.Fatalf("expected limit to stop at the ceiling of 12 but got %d", limit)
+	}
+
+	for i := 0; i < 100; i++ {
+		l.report(time.Second, true, l.Limit())
+	}
+	if limit := l.Limit(); limit != 5 {
+		t.Fatalf("expected limit to stop at the floor of 5 but got %d", limit)
+	}
+
+	// not limited by the limit, no change.
+	before := l.Estimate()
+	l.report(time.Millisecond, false, 1)
+	if after := l.Estimate(); after != before {
+		t.Fatalf("expected the estimate to not change but got %f from %f", after, before)
+	}
+}
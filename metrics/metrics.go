@@ -0,0 +1,236 @@
+// Package metrics exports the stats of chronos limiters in the Prometheus text exposition format,
+// it has no any dependencies except the standard library.
+//
+// Example Code:
+//
+//	c := chronos.New(150, time.Minute)
+//	metrics.Register("ip-api", c)
+//	http.Handle("/metrics", metrics.Handler())
+//
+// The limiters of the global registry, see `chronos.Register`,
+// are exported by the `Default` Exporter too, under their name and an empty key.
+//
+// Exported metrics, all of them have the "limiter" and "key" labels:
+//
+//	chronos_acquired_total    counter   operations allowed.
+//	chronos_rejected_total    counter   operations not allowed or rejected, see `Stats.Rejected`.
+//	chronos_waited_total      counter   operations that had to wait before allowed.
+//	chronos_dry_run_total     counter   operations that exceeded the limit but allowed in dry-run mode.
+//	chronos_limit             gauge     the maximum operations per circle.
+//	chronos_usage             gauge     operations counted in the current circle.
+//	chronos_queue_depth       gauge     operations that currently wait.
+//	chronos_wait_seconds      histogram the wait durations of the `Acquire`.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kataras/chronos"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type entry struct {
+	name string
+	key  string
+	c    *chronos.C
+}
+
+// Exporter keeps the limiters to be exported, it's an `http.Handler`.
+type Exporter struct {
+	// Global reports whether the limiters of the global registry, see `chronos.Register`,
+	// are exported too. A limiter registered to the Exporter with the same name
+	// and an empty key takes precedence. It's true for the `Default` Exporter.
+	Global bool
+
+	mu      sync.RWMutex
+	entries map[[2]string]entry
+}
+
+// New returns a new, empty, Exporter.
+func New() *Exporter {
+	return &Exporter{entries: make(map[[2]string]entry)}
+}
+
+// Default is the Exporter of the package-level functions,
+// it exports the limiters of the global registry too.
+var Default = &Exporter{Global: true, entries: make(map[[2]string]entry)}
+
+// Register adds the "c" limiter to be exported with the "name" label.
+func (e *Exporter) Register(name string, c *chronos.C) {
+	e.RegisterKey(name, "", c)
+}
+
+// RegisterKey adds the "c" limiter to be exported with the "name" and "key" labels,
+// it's useful for limiters per user, per IP and e.t.c.
+// A limiter with the same name and key is replaced.
+func (e *Exporter) RegisterKey(name, key string, c *chronos.C) {
+	e.mu.Lock()
+	e.entries[[2]string{name, key}] = entry{name: name, key: key, c: c}
+	e.mu.Unlock()
+}
+
+// Unregister removes the limiter of the "name" and "key" labels.
+func (e *Exporter) Unregister(name, key string) {
+	e.mu.Lock()
+	delete(e.entries, [2]string{name, key})
+	e.mu.Unlock()
+}
+
+func (e *Exporter) sorted() []entry {
+	e.mu.RLock()
+	entries := make([]entry, 0, len(e.entries))
+	for _, en := range e.entries {
+		entries = append(entries, en)
+	}
+	e.mu.RUnlock()
+
+	if e.Global {
+		chronos.Range(func(name string, c *chronos.C) bool {
+			e.mu.RLock()
+			_, ok := e.entries[[2]string{name, ""}]
+			e.mu.RUnlock()
+			if !ok {
+				entries = append(entries, entry{name: name, c: c})
+			}
+			return true
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].name == entries[j].name {
+			return entries[i].key < entries[j].key
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	return entries
+}
+
+type sample struct {
+	labels string
+	stats  chronos.Stats
+}
+
+type metric struct {
+	name  string
+	help  string
+	typ   string
+	value func(st chronos.Stats) uint64
+}
+
+var metrics = []metric{
+	{"chronos_acquired_total", "Operations allowed by the limiter.", "counter", func(st chronos.Stats) uint64 { return st.Acquired }},
+	{"chronos_rejected_total", "Operations not allowed by the limiter.", "counter", func(st chronos.Stats) uint64 { return st.Rejected }},
+	{"chronos_waited_total", "Operations that had to wait before allowed.", "counter", func(st chronos.Stats) uint64 { return st.Waited }},
+	{"chronos_dry_run_total", "Operations that exceeded the limit but allowed in dry-run mode.", "counter", func(st chronos.Stats) uint64 { return st.DryRuns }},
+	{"chronos_limit", "Maximum operations per circle.", "gauge", func(st chronos.Stats) uint64 { return uint64(st.Max) }},
+	{"chronos_usage", "Operations counted in the current circle.", "gauge", func(st chronos.Stats) uint64 { return uint64(st.Length) }},
+	{"chronos_queue_depth", "Operations that currently wait.", "gauge", func(st chronos.Stats) uint64 {
+		if st.Waiting < 0 {
+			return 0
+		}
+		return uint64(st.Waiting)
+	}},
+}
+
+// WriteTo writes the metrics of all registered limiters to "w".
+func (e *Exporter) WriteTo(w io.Writer) (int64, error) {
+	entries := e.sorted()
+	samples := make([]sample, len(entries))
+	for i, en := range entries {
+		samples[i] = sample{
+			labels: `limiter="` + escape(en.name) + `",key="` + escape(en.key) + `"`,
+			stats:  en.c.Stats(),
+		}
+	}
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	for _, m := range metrics {
+		writeHeader(bw, m.name, m.help, m.typ)
+		for _, s := range samples {
+			bw.WriteString(m.name + "{" + s.labels + "} " + strconv.FormatUint(m.value(s.stats), 10) + "\n")
+		}
+	}
+
+	const histogram = "chronos_wait_seconds"
+	writeHeader(bw, histogram, "Wait durations of the acquire.", "histogram")
+	for _, s := range samples {
+		h := s.stats.Wait
+		var cumulative uint64
+		for i, count := range h.Counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(h.Buckets) {
+				le = formatFloat(h.Buckets[i].Seconds())
+			}
+			bw.WriteString(histogram + "_bucket{" + s.labels + `,le="` + le + `"} ` + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		bw.WriteString(histogram + "_sum{" + s.labels + "} " + formatFloat(h.Sum.Seconds()) + "\n")
+		bw.WriteString(histogram + "_count{" + s.labels + "} " + strconv.FormatUint(h.Count, 10) + "\n")
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP implements the `http.Handler` interface.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	e.WriteTo(w)
+}
+
+// Register adds the "c" limiter to the `Default` Exporter.
+func Register(name string, c *chronos.C) {
+	Default.Register(name, c)
+}
+
+// RegisterKey adds the "c" limiter to the `Default` Exporter with a "key" label.
+func RegisterKey(name, key string, c *chronos.C) {
+	Default.RegisterKey(name, key, c)
+}
+
+// Unregister removes a limiter from the `Default` Exporter.
+func Unregister(name, key string) {
+	Default.Unregister(name, key)
+}
+
+// Handler returns the `Default` Exporter as an `http.Handler`.
+func Handler() http.Handler {
+	return Default
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
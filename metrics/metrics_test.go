@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kataras/chronos"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func golden(t *testing.T, name string, got []byte) {
+	filename := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := ioutil.WriteFile(filename, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expected, got) {
+		t.Fatalf("output does not match the %s, run with -update if that's expected, got:\n%s", filename, got)
+	}
+}
+
+func TestExporter(t *testing.T) {
+	e := New()
+
+	api := chronos.New(3, time.Hour)
+	for i := 0; i < 5; i++ {
+		api.Allow()
+	}
+	e.Register("api", api)
+
+	user := chronos.New(10, time.Hour)
+	user.Allow()
+	e.RegisterKey("users", `john "doe"`, user)
+	e.RegisterKey("users", "", chronos.New(10, time.Hour))
+
+	e.RegisterKey("removed", "", chronos.New(1, time.Hour))
+	e.Unregister("removed", "")
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if expected, got := ContentType, rec.Header().Get("Content-Type"); expected != got {
+		t.Fatalf("expected content type %s but got %s", expected, got)
+	}
+
+	golden(t, "exporter", rec.Body.Bytes())
+}
+
+func TestExporterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := New().WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if int(n) != buf.Len() {
+		t.Fatalf("expected written bytes to be %d but got %d", buf.Len(), n)
+	}
+
+	golden(t, "empty", buf.Bytes())
+}
+
+func TestExporterGlobal(t *testing.T) {
+	global := chronos.New(7, time.Hour)
+	if err := chronos.Register("global", global); err != nil {
+		t.Fatal(err)
+	}
+	defer chronos.Unregister("global")
+
+	e := New()
+	var buf bytes.Buffer
+	e.WriteTo(&buf)
+	if bytes.Contains(buf.Bytes(), []byte(`limiter="global"`)) {
+		t.Fatalf("expected the global registry to not be exported by default")
+	}
+
+	e.Global = true
+	buf.Reset()
+	e.WriteTo(&buf)
+	if expected := `chronos_limit{limiter="global",key=""} 7`; !bytes.Contains(buf.Bytes(), []byte(expected)) {
+		t.Fatalf("expected %q in:\n%s", expected, buf.String())
+	}
+
+	e.Register("global", chronos.New(9, time.Hour))
+	buf.Reset()
+	e.WriteTo(&buf)
+	if expected := `chronos_limit{limiter="global",key=""} 9`; !bytes.Contains(buf.Bytes(), []byte(expected)) {
+		t.Fatalf("expected the Exporter's limiter to take precedence, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,181 @@
+package chronos
+
+import (
+	"reflect"
+	"time"
+)
+
+// Observer is the interface that can be attached to a limiter,
+// through `Observe`, in order to be notified about its decisions,
+// i.e for logging and tracing.
+//
+// The methods are called outside of the limiter's locks,
+// from the goroutine which made the decision, they should not block.
+type Observer interface {
+	// OnAcquire is called when "n" operations are allowed,
+	// the "wait" is the time that the operations waited before allowed.
+	OnAcquire(c *C, n uint32, wait time.Duration)
+	// OnWait is called when an operation is scheduled to retry after the "delay".
+	OnWait(c *C, delay time.Duration)
+	// OnReject is called when "n" operations are not allowed: by `Allow` or `TakeN`,
+	// or rejected by `Acquire`, `Reserve` or `WaitN`, i.e when the limiter is paused
+	// in the `PauseReject` mode or draining, the queue is full or the cost exceeds the limit.
+	OnReject(c *C, n uint32)
+	// OnCircle is called when a new circle is drawn.
+	OnCircle(c *C, circle uint64)
+	// OnClose is called when the limiter is closed.
+	OnClose(c *C)
+}
+
+// DryRunObserver can be implemented by an `Observer`
+// to be notified about the decisions of the dry-run mode, see `SetDryRun`.
+type DryRunObserver interface {
+	// OnDryRun is called when "n" operations exceeded the limit but they were allowed,
+	// the "retryAfter" is the time that they would wait if the limit was enforced.
+	// The `OnAcquire` is called after that, as usual.
+	OnDryRun(c *C, n uint32, retryAfter time.Duration)
+}
+
+// WaitObserver can be implemented by an `Observer`
+// to be notified about the cost of the scheduled operations.
+type WaitObserver interface {
+	// OnWaitN is called instead of the `OnWait` when "n" operations,
+	// i.e of a `WaitN` or a `WaitCost`, are scheduled to retry after the "delay".
+	OnWaitN(c *C, n uint32, delay time.Duration)
+}
+
+// ObserverFuncs is an `Observer` made of optional functions,
+// a nil function is skipped.
+type ObserverFuncs struct {
+	Acquire func(c *C, n uint32, wait time.Duration)
+	Wait    func(c *C, delay time.Duration)
+	Reject  func(c *C, n uint32)
+	Circle  func(c *C, circle uint64)
+	Close   func(c *C)
+	DryRun  func(c *C, n uint32, retryAfter time.Duration)
+}
+
+var (
+	_ Observer       = ObserverFuncs{}
+	_ DryRunObserver = ObserverFuncs{}
+)
+
+// OnAcquire implements the `Observer` interface.
+func (o ObserverFuncs) OnAcquire(c *C, n uint32, wait time.Duration) {
+	if o.Acquire != nil {
+		o.Acquire(c, n, wait)
+	}
+}
+
+// OnWait implements the `Observer` interface.
+func (o ObserverFuncs) OnWait(c *C, delay time.Duration) {
+	if o.Wait != nil {
+		o.Wait(c, delay)
+	}
+}
+
+// OnReject implements the `Observer` interface.
+func (o ObserverFuncs) OnReject(c *C, n uint32) {
+	if o.Reject != nil {
+		o.Reject(c, n)
+	}
+}
+
+// OnCircle implements the `Observer` interface.
+func (o ObserverFuncs) OnCircle(c *C, circle uint64) {
+	if o.Circle != nil {
+		o.Circle(c, circle)
+	}
+}
+
+// OnClose implements the `Observer` interface.
+func (o ObserverFuncs) OnClose(c *C) {
+	if o.Close != nil {
+		o.Close(c)
+	}
+}
+
+// OnDryRun implements the `DryRunObserver` interface.
+func (o ObserverFuncs) OnDryRun(c *C, n uint32, retryAfter time.Duration) {
+	if o.DryRun != nil {
+		o.DryRun(c, n, retryAfter)
+	}
+}
+
+// Observe attaches the "o" to the limiter.
+// It's safe to be called while the limiter is in use.
+func (c *C) Observe(o Observer) {
+	c.mu.Lock()
+	old, _ := c.observers.Load().([]Observer)
+	observers := make([]Observer, len(old), len(old)+1)
+	copy(observers, old)
+	c.observers.Store(append(observers, o))
+	c.mu.Unlock()
+}
+
+// Unobserve detaches the "o", which was attached by `Observe`, from the limiter.
+// The "o" should be comparable, i.e a pointer, otherwise it does nothing.
+// It's safe to be called while the limiter is in use.
+func (c *C) Unobserve(o Observer) {
+	if o == nil || !reflect.TypeOf(o).Comparable() {
+		return
+	}
+
+	c.mu.Lock()
+	old, _ := c.observers.Load().([]Observer)
+	observers := make([]Observer, 0, len(old))
+	for _, attached := range old {
+		if attached != o {
+			observers = append(observers, attached)
+		}
+	}
+	c.observers.Store(observers)
+	c.mu.Unlock()
+}
+
+func (c *C) getObservers() []Observer {
+	observers, _ := c.observers.Load().([]Observer)
+	return observers
+}
+
+func (c *C) notifyAcquire(n uint32, wait time.Duration) {
+	for _, o := range c.getObservers() {
+		o.OnAcquire(c, n, wait)
+	}
+}
+
+func (c *C) notifyWait(n uint32, delay time.Duration) {
+	for _, o := range c.getObservers() {
+		if w, ok := o.(WaitObserver); ok {
+			w.OnWaitN(c, n, delay)
+			continue
+		}
+		o.OnWait(c, delay)
+	}
+}
+
+func (c *C) notifyReject(n uint32) {
+	for _, o := range c.getObservers() {
+		o.OnReject(c, n)
+	}
+}
+
+func (c *C) notifyCircle(circle uint64) {
+	for _, o := range c.getObservers() {
+		o.OnCircle(c, circle)
+	}
+}
+
+func (c *C) notifyClose() {
+	for _, o := range c.getObservers() {
+		o.OnClose(c)
+	}
+}
+
+func (c *C) notifyDryRun(n uint32, retryAfter time.Duration) {
+	for _, o := range c.getObservers() {
+		if d, ok := o.(DryRunObserver); ok {
+			d.OnDryRun(c, n, retryAfter)
+		}
+	}
+}
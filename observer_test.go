@@ -0,0 +1,152 @@
+package chronos
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(format string, a ...interface{}) {
+	r.mu.Lock()
+	r.events = append(r.events, fmt.Sprintf(format, a...))
+	r.mu.Unlock()
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func TestObserver(t *testing.T) {
+	per := 200 * time.Millisecond
+	c := New(1, per)
+
+	r := new(recorder)
+	c.Observe(ObserverFuncs{
+		Acquire: func(c *C, n uint32, wait time.Duration) {
+			// the hooks run outside of the limiter's locks.
+			st := c.Stats()
+			r.add("acquire:%d:%d:%v", n, st.Length, wait >= per/2)
+		},
+		Wait:   func(c *C, delay time.Duration) { r.add("wait:%v", delay > 0 && delay <= per) },
+		Reject: func(c *C, n uint32) { r.add("reject:%d", n) },
+		Circle: func(c *C, circle uint64) { r.add("circle:%d", circle) },
+		Close:  func(c *C) { r.add("close") },
+	})
+
+	<-c.Acquire()
+	c.Allow()
+	<-c.Acquire()
+	c.Close()
+	c.Close()
+
+	if _, ok := <-c.Acquire(); ok {
+		t.Fatalf("expected a closed channel after close")
+	}
+
+	if c.Allow() {
+		t.Fatalf("expected allow to report false after close")
+	}
+
+	expected := []string{
+		"acquire:1:1:false",
+		"reject:1",
+		"wait:true",
+		"circle:1",
+		"acquire:1:1:true",
+		"close",
+	}
+
+	got := r.get()
+	if fmt.Sprint(expected) != fmt.Sprint(got) {
+		t.Fatalf("expected events:\n%v\nbut got:\n%v", expected, got)
+	}
+}
+
+func TestUnobserve(t *testing.T) {
+	c := New(10, time.Minute)
+
+	r := new(recorder)
+	o := &ObserverFuncs{Reject: func(c *C, n uint32) { r.add("reject:%d", n) }}
+	c.Observe(o)
+	// not comparable, it's left attached.
+	c.Observe(ObserverFuncs{})
+	c.Unobserve(ObserverFuncs{})
+
+	c.AllowN(11)
+	c.Unobserve(o)
+	c.AllowN(11)
+
+	if expected, got := []string{"reject:11"}, r.get(); fmt.Sprint(expected) != fmt.Sprint(got) {
+		t.Fatalf("expected events %v but got %v", expected, got)
+	}
+	if n := len(c.getObservers()); n != 1 {
+		t.Fatalf("expected 1 observer left but got %d", n)
+	}
+}
+
+func BenchmarkAllow(b *testing.B) {
+	c := New(1<<31, time.Hour)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Allow()
+	}
+}
+
+func BenchmarkAllowObserved(b *testing.B) {
+	c := New(1<<31, time.Hour)
+	c.Observe(ObserverFuncs{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Allow()
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	per := time.Minute
+	c := New(1, per)
+	c.SetDryRun(true)
+
+	var (
+		dryRuns    uint32
+		retryAfter time.Duration
+	)
+	c.Observe(ObserverFuncs{DryRun: func(c *C, n uint32, d time.Duration) {
+		dryRuns += n
+		retryAfter = d
+	}})
+
+	if !c.Allow() || !c.Allow() || c.TakeN(3) != nil {
+		t.Fatalf("expected all operations to be allowed in dry-run mode")
+	}
+
+	select {
+	case <-c.Acquire():
+	case <-time.After(time.Second):
+		t.Fatalf("expected acquire to not wait in dry-run mode")
+	}
+
+	if dryRuns != 5 || retryAfter <= 0 || retryAfter > per {
+		t.Fatalf("expected 5 dry-run operations but got %d, retry after %s", dryRuns, retryAfter)
+	}
+
+	if st := c.Stats(); !st.DryRun || st.DryRuns != 5 || st.Acquired != 6 || st.Length != 1 || st.Waited != 0 {
+		t.Fatalf("unexpected stats: %#+v", st)
+	}
+
+	c.SetDryRun(false)
+	if c.Allow() {
+		t.Fatalf("expected the limit to be enforced after dry-run mode is disabled")
+	}
+}
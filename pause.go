@@ -0,0 +1,118 @@
+package chronos
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// PauseMode is how a paused limiter treats the new operations, see `C.PauseMode`.
+type PauseMode uint8
+
+const (
+	// PauseQueue makes the new `Acquire` and `Wait` calls wait until `Resume`.
+	PauseQueue PauseMode = iota
+	// PauseReject rejects the new operations, the `Acquire`
+	// is released with a closed channel and the `Wait` fails with `ErrPaused`.
+	PauseReject
+)
+
+// admit reports why a new operation is not admitted, if so.
+// It must be called under lock.
+func (c *C) admit() error {
+	if c.draining {
+		return ErrDraining
+	}
+
+	if c.paused && c.PauseMode == PauseReject {
+		return ErrPaused
+	}
+
+	return nil
+}
+
+// hold keeps the "w" until `Resume`, it must be called under lock.
+func (c *C) hold(w *waiter) {
+	c.enqueue(w)
+	c.held = append(c.held, w)
+}
+
+// Pause stops allowing operations until `Resume`,
+// the `Allow` reports false and the new `Acquire` calls wait or are rejected,
+// depending on the `PauseMode`. The already scheduled operations wait too.
+func (c *C) Pause() {
+	c.mu.Lock()
+	c.paused = true
+	c.mu.Unlock()
+}
+
+// Resume allows operations again, after `Pause` or `Drain`.
+// The operations that waited while paused are allowed
+// at the limiter's rate, not all at once.
+func (c *C) Resume() {
+	c.mu.Lock()
+	c.paused = false
+	c.draining = false
+	held := c.held
+	c.held = nil
+	c.mu.Unlock()
+
+	c.release(held)
+}
+
+// release retries the "held" waiters.
+func (c *C) release(held []*waiter) {
+	for _, w := range held {
+		c.tryAcquire(w)
+	}
+}
+
+// IsPaused reports whether the limiter is paused.
+func (c *C) IsPaused() bool {
+	c.mu.RLock()
+	paused := c.paused
+	c.mu.RUnlock()
+	return paused
+}
+
+// drainInterval is the time between two checks of the queue by `Drain`.
+const drainInterval = 10 * time.Millisecond
+
+// Drain stops admitting new operations, they are rejected with `ErrDraining`,
+// and it blocks until the operations that already wait are allowed,
+// at the limiter's rate, or until the "ctx" is done.
+// A paused limiter is resumed, so its waiting operations can be allowed.
+//
+// The limiter keeps rejecting new operations after `Drain` returns,
+// until `Resume`. Use `Close` to release the waiting operations immediately instead.
+func (c *C) Drain(ctx context.Context) error {
+	c.mu.Lock()
+	c.draining = true
+	c.paused = false
+	held := c.held
+	c.held = nil
+	c.mu.Unlock()
+
+	c.release(held)
+
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	for atomic.LoadInt64(&c.waiting) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// IsDraining reports whether the limiter is draining, see `Drain`.
+func (c *C) IsDraining() bool {
+	c.mu.RLock()
+	draining := c.draining
+	c.mu.RUnlock()
+	return draining
+}
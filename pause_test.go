@@ -0,0 +1,126 @@
+package chronos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPauseResume(t *testing.T) {
+	c := New(1, time.Second)
+	c.Pause()
+
+	if c.Allow() {
+		t.Fatalf("expected operation to not be allowed while paused")
+	}
+
+	ch := c.Acquire()
+	select {
+	case <-ch:
+		t.Fatalf("expected acquire to wait while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if st := c.Stats(); !st.Paused || st.Waiting != 1 || st.Length != 0 {
+		t.Fatalf("unexpected stats while paused: %#+v", st)
+	}
+
+	c.Resume()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatalf("expected acquire to be allowed after resume")
+	}
+
+	if st := c.Stats(); st.Paused || st.Waiting != 0 || st.Length != 1 {
+		t.Fatalf("unexpected stats after resume: %#+v", st)
+	}
+}
+
+func TestPauseReject(t *testing.T) {
+	c := New(1, time.Second)
+	c.PauseMode = PauseReject
+	c.Pause()
+
+	if _, ok := <-c.Acquire(); ok {
+		t.Fatalf("expected acquire to be rejected with a closed channel")
+	}
+
+	if err := c.Wait(context.Background()); !errors.Is(err, ErrPaused) {
+		t.Fatalf("expected ErrPaused but got %v", err)
+	}
+
+	if err := c.Take(); err != ErrPaused {
+		t.Fatalf("expected ErrPaused but got %v", err)
+	}
+
+	if wait := c.Reserve(); wait != Never {
+		t.Fatalf("expected reserve to return Never but got %s", wait)
+	}
+
+	if st := c.Stats(); st.Rejected != 4 || st.Waiting != 0 || st.Length != 0 {
+		t.Fatalf("unexpected stats: %#+v", st)
+	}
+
+	c.Resume()
+	if err := c.Take(); err != nil {
+		t.Fatalf("expected operation to be allowed after resume but got %v", err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	per := 200 * time.Millisecond
+	c := New(1, per)
+	c.Pause()
+
+	// two waiting operations, released at the limiter's rate by the Drain.
+	first, second := c.Acquire(), c.Acquire()
+	for c.Stats().Waiting != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() { done <- c.Drain(context.Background()) }()
+
+	for !c.IsDraining() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if st := c.Stats(); !st.Draining || st.Paused {
+		t.Fatalf("unexpected stats while draining: %#+v", st)
+	}
+
+	if err := c.Take(); err != ErrDraining {
+		t.Fatalf("expected ErrDraining but got %v", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < per {
+		t.Fatalf("expected the waiting operations to be allowed at the limiter's rate but took %s", elapsed)
+	}
+
+	<-first
+	<-second
+
+	if _, ok := <-c.Acquire(); ok {
+		t.Fatalf("expected acquire to be rejected after drain")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c.Resume()
+	c.Pause()
+	c.Acquire()
+	for c.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := c.Drain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the drain to be canceled but got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package chronos
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrDuplicateName is returned by `Register`
+// when a limiter is already registered under the same name.
+var ErrDuplicateName = errors.New("chronos: a limiter with the same name is already registered")
+
+var registry = struct {
+	mu       sync.RWMutex
+	limiters map[string]*C
+}{limiters: make(map[string]*C)}
+
+// Register adds the "c" limiter to the global registry under the "name",
+// so it can be found by `Get` and it's shown by the metrics, expvar and debug packages.
+// It returns `ErrDuplicateName` if the "name" is already in use, see `Unregister`.
+func Register(name string, c *C) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.limiters[name]; ok {
+		return ErrDuplicateName
+	}
+
+	registry.limiters[name] = c
+	return nil
+}
+
+// MustRegister is like `Register` but it panics on error,
+// it returns the "c" limiter.
+//
+//	var apiLimiter = chronos.MustRegister("api", chronos.New(150, time.Minute))
+func MustRegister(name string, c *C) *C {
+	if err := Register(name, c); err != nil {
+		panic(err.Error() + ": " + name)
+	}
+	return c
+}
+
+// Unregister removes the limiter which is registered under the "name",
+// it reports whether a limiter was removed.
+// Note that the limiter is not closed.
+func Unregister(name string) bool {
+	registry.mu.Lock()
+	_, ok := registry.limiters[name]
+	delete(registry.limiters, name)
+	registry.mu.Unlock()
+	return ok
+}
+
+// Get returns the limiter which is registered under the "name".
+func Get(name string) (*C, bool) {
+	registry.mu.RLock()
+	c, ok := registry.limiters[name]
+	registry.mu.RUnlock()
+	return c, ok
+}
+
+// Names returns the sorted names of the registered limiters.
+func Names() []string {
+	registry.mu.RLock()
+	names := make([]string, 0, len(registry.limiters))
+	for name := range registry.limiters {
+		names = append(names, name)
+	}
+	registry.mu.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// Range calls the "fn" for each registered limiter, sorted by name,
+// until it returns false. The registry is not locked while the "fn" runs,
+// so it's free to register or unregister limiters.
+func Range(fn func(name string, c *C) bool) {
+	for _, name := range Names() {
+		c, ok := Get(name)
+		if !ok {
+			continue
+		}
+
+		if !fn(name, c) {
+			return
+		}
+	}
+}
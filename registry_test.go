@@ -0,0 +1,49 @@
+package chronos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	a, b := New(1, time.Second), New(2, time.Second)
+
+	if err := Register("test-b", b); err != nil {
+		t.Fatal(err)
+	}
+	defer Unregister("test-b")
+
+	if err := Register("test-a", a); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Register("test-a", b); err != ErrDuplicateName {
+		t.Fatalf("expected ErrDuplicateName but got %v", err)
+	}
+
+	if c, ok := Get("test-a"); !ok || c != a {
+		t.Fatalf("expected the registered limiter to be found")
+	}
+
+	var names []string
+	Range(func(name string, c *C) bool {
+		names = append(names, name)
+		return true
+	})
+	if len(names) != 2 || names[0] != "test-a" || names[1] != "test-b" {
+		t.Fatalf("expected sorted names but got %v", names)
+	}
+
+	if !Unregister("test-a") || Unregister("test-a") {
+		t.Fatalf("expected the limiter to be unregistered once")
+	}
+
+	if _, ok := Get("test-a"); ok {
+		t.Fatalf("expected the limiter to not be found after unregister")
+	}
+
+	if err := Register("test-a", b); err != nil {
+		t.Fatalf("expected the name to be available again but got %v", err)
+	}
+	Unregister("test-a")
+}
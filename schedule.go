@@ -0,0 +1,239 @@
+package chronos
+
+import (
+	"sync"
+	"time"
+)
+
+// Profile is a limit of a `Schedule`.
+type Profile struct {
+	Max uint32        // maximum operations
+	Per time.Duration // per x time.
+	// Blackout, if true, allows nothing, the limiter is paused, see `C#Pause`.
+	Blackout bool
+}
+
+// Rule applies a `Profile` on a weekly time range of a `Schedule`.
+type Rule struct {
+	// Days are the days of the rule, empty means every day.
+	Days []time.Weekday
+	// From and To are the time range of the rule, since the midnight of the wall clock,
+	// i.e 22 * time.Hour for 22:00. A "To" before the "From" ends on the next day,
+	// i.e from 22:00 to 06:00, the same "From" and "To" mean the whole day.
+	From, To time.Duration
+
+	Profile
+}
+
+// Schedule is a weekly schedule of profiles, i.e peak and off-peak limits.
+type Schedule struct {
+	// Location is the time zone of the rules, defaults to UTC.
+	Location *time.Location
+	// Default is the profile when no rule applies.
+	Default Profile
+	// Rules are the scheduled profiles, the first rule that applies wins.
+	Rules []Rule
+}
+
+func (r Rule) has(day time.Weekday) bool {
+	if len(r.Days) == 0 {
+		return true
+	}
+
+	for _, d := range r.Days {
+		if d == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applies reports whether the rule applies on the "day" at the "clock" of the wall clock.
+func (r Rule) applies(day time.Weekday, clock time.Duration) bool {
+	switch {
+	case r.From == r.To:
+		return r.has(day)
+	case r.From < r.To:
+		return r.has(day) && clock >= r.From && clock < r.To
+	default:
+		// it's started the previous day or it starts this day.
+		return (r.has(day) && clock >= r.From) || (r.has((day+6)%7) && clock < r.To)
+	}
+}
+
+func clockOf(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// wallClock returns the "clock" of a date as a time without a zone, so wall clocks can be compared.
+func wallClock(y int, m time.Month, d int, clock time.Duration) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Add(clock)
+}
+
+// At returns the profile of the "t" time
+// and the time that the profile may change.
+func (s Schedule) At(t time.Time) (p Profile, next time.Time) {
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	t = t.In(loc)
+	p = s.Default
+	day, clock := t.Weekday(), clockOf(t)
+	for _, r := range s.Rules {
+		if r.applies(day, clock) {
+			p = r.Profile
+			break
+		}
+	}
+
+	// the next change is the nearest boundary of a rule, or the next midnight.
+	y, m, d := t.Date()
+	next = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	for _, r := range s.Rules {
+		for _, boundary := range [...]time.Duration{r.From, r.To} {
+			if boundary <= clock || !r.has(day) && (boundary != r.To || !r.has((day+6)%7)) {
+				continue // passed or not a boundary of this day.
+			}
+
+			h, rest := boundary/time.Hour, boundary%time.Hour
+			at := time.Date(y, m, d, int(h), 0, 0, 0, loc).Add(rest)
+			// a boundary which is skipped by a DST gap is passed
+			// at the first instant after the gap, the start of the next zone.
+			wall := wallClock(y, m, d, boundary)
+			atWall := wallClock(at.Year(), at.Month(), at.Day(), clockOf(at))
+			if atWall.Before(wall) {
+				_, at = at.ZoneBounds()
+			} else if atWall.After(wall) {
+				at, _ = at.ZoneBounds()
+			}
+			if at.After(t) && at.Before(next) {
+				next = at
+			}
+		}
+	}
+
+	return p, next
+}
+
+// Scheduler applies the profiles of a `Schedule` to a limiter, as the time goes by,
+// through its `SetLimit`, `Pause` and `Resume`.
+// The operations that already wait are retried with the new limit,
+// the ones that wait a blackout are allowed, at the limiter's rate, when it ends.
+//
+// Example Code:
+//
+//	c := chronos.New(100, time.Minute)
+//	s := chronos.NewScheduler(c, chronos.Schedule{
+//		Location: newYork,
+//		Default:  chronos.Profile{Max: 100, Per: time.Minute},
+//		Rules: []chronos.Rule{
+//			{From: 22 * time.Hour, To: 6 * time.Hour, Profile: chronos.Profile{Max: 500, Per: time.Minute}},
+//			{Days: []time.Weekday{time.Sunday}, From: 3 * time.Hour, To: 4 * time.Hour, Profile: chronos.Profile{Blackout: true}},
+//		},
+//	})
+//	defer s.Close()
+type Scheduler struct {
+	c        *C
+	schedule Schedule
+
+	mu      sync.Mutex
+	profile Profile
+	applied bool
+	paused  bool // paused by the scheduler.
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	done      chan struct{}
+}
+
+// NewScheduler applies the current profile of the "schedule" to the "c" limiter
+// and keeps applying the next ones until `Close`.
+func NewScheduler(c *C, schedule Schedule) *Scheduler {
+	s := &Scheduler{
+		c:        c,
+		schedule: schedule,
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	next := s.apply(time.Now())
+	go s.run(next)
+	return s
+}
+
+// apply applies the profile of the "now" time and returns when it may change.
+func (s *Scheduler) apply(now time.Time) time.Time {
+	p, next := s.schedule.At(now)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.applied && s.profile == p {
+		return next
+	}
+	s.profile, s.applied = p, true
+
+	if p.Blackout {
+		if !s.paused {
+			s.paused = true
+			s.c.Pause()
+		}
+		return next
+	}
+
+	s.c.SetLimit(p.Max, p.Per)
+	if s.paused {
+		s.paused = false
+		s.c.Resume()
+	}
+
+	return next
+}
+
+func (s *Scheduler) run(next time.Time) {
+	defer close(s.done)
+
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-timer.C:
+		}
+
+		next = s.apply(time.Now())
+		timer.Reset(time.Until(next))
+	}
+}
+
+// Profile returns the profile which is applied now.
+func (s *Scheduler) Profile() Profile {
+	s.mu.Lock()
+	p := s.profile
+	s.mu.Unlock()
+	return p
+}
+
+// Close stops the scheduler, the limiter keeps its current limit
+// and it's resumed if it's paused by a blackout.
+func (s *Scheduler) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		<-s.done
+
+		s.mu.Lock()
+		if s.paused {
+			s.paused = false
+			s.c.Resume()
+		}
+		s.mu.Unlock()
+	})
+	return nil
+}
@@ -0,0 +1,148 @@
+package chronos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleAt(t *testing.T) {
+	var (
+		offPeak  = Profile{Max: 10, Per: time.Minute}
+		peak     = Profile{Max: 5, Per: time.Minute}
+		night    = Profile{Max: 20, Per: time.Minute}
+		blackout = Profile{Blackout: true}
+	)
+
+	s := Schedule{
+		Default: offPeak,
+		Rules: []Rule{
+			{Days: []time.Weekday{time.Sunday}, From: 3 * time.Hour, To: 4 * time.Hour, Profile: blackout},
+			{Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, From: 9 * time.Hour, To: 17 * time.Hour, Profile: peak},
+			{From: 22 * time.Hour, To: 6 * time.Hour, Profile: night},
+		},
+	}
+
+	at := func(day, hour, min int) time.Time {
+		// 2024-01-01 is a Monday.
+		return time.Date(2024, time.January, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		t       time.Time
+		profile Profile
+		next    time.Time
+	}{
+		{at(1, 8, 0), offPeak, at(1, 9, 0)},
+		{at(1, 10, 30), peak, at(1, 17, 0)},
+		{at(1, 17, 0), offPeak, at(1, 22, 0)},
+		{at(1, 23, 0), night, at(2, 0, 0)},
+		{at(2, 2, 0), night, at(2, 6, 0)},     // started the previous day.
+		{at(6, 10, 0), offPeak, at(6, 17, 0)}, // Saturday, the next is a boundary of the peak rule.
+		{at(7, 3, 30), blackout, at(7, 4, 0)},
+		{at(7, 4, 0), night, at(7, 6, 0)},
+	}
+
+	for i, tt := range tests {
+		p, next := s.At(tt.t)
+		if p != tt.profile {
+			t.Fatalf("[%d] expected profile %#+v but got %#+v", i, tt.profile, p)
+		}
+		if !next.Equal(tt.next) {
+			t.Fatalf("[%d] expected next change at %s but got %s", i, tt.next, next)
+		}
+	}
+
+	// time zone.
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Location = newYork
+	if p, _ := s.At(at(1, 15, 0)); p != peak { // 10:00 in New York.
+		t.Fatalf("expected the peak profile in New York but got %#+v", p)
+	}
+}
+
+func TestScheduleAtDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	night := Profile{Max: 5, Per: time.Minute}
+	s := Schedule{
+		Location: newYork,
+		Default:  Profile{Max: 1, Per: time.Minute},
+		Rules:    []Rule{{From: 2*time.Hour + 30*time.Minute, To: 4 * time.Hour, Profile: night}},
+	}
+
+	// 02:30 is skipped on the DST start, 02:00 EST becomes 03:00 EDT.
+	now := time.Date(2026, 3, 8, 1, 30, 0, 0, newYork)
+	_, next := s.At(now)
+	if expected := time.Date(2026, 3, 8, 3, 0, 0, 0, newYork); !next.Equal(expected) {
+		t.Fatalf("expected the next change at %s but got %s", expected, next)
+	}
+
+	if p, _ := s.At(next); p != night {
+		t.Fatalf("expected the rule to apply after the DST gap but got %#+v", p)
+	}
+}
+
+// noon returns a time zone which the current time is around noon,
+// so the rules from 11:00 to 13:00 apply now.
+func noon() *time.Location {
+	offset := 12*time.Hour - clockOf(time.Now().UTC())
+	return time.FixedZone("noon", int(offset/time.Second))
+}
+
+func TestScheduler(t *testing.T) {
+	c := New(1, time.Hour)
+	if !c.Allow() {
+		t.Fatalf("expected first operation to be allowed")
+	}
+
+	ch := c.Acquire() // waits an hour.
+
+	s := NewScheduler(c, Schedule{
+		Location: noon(),
+		Default:  Profile{Max: 1, Per: time.Hour},
+		Rules: []Rule{
+			{From: 11 * time.Hour, To: 13 * time.Hour, Profile: Profile{Max: 2, Per: time.Hour}},
+		},
+	})
+	defer s.Close()
+
+	if p := s.Profile(); p.Max != 2 {
+		t.Fatalf("expected the scheduled profile but got %#+v", p)
+	}
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the waiting operation to be allowed with the scheduled limit")
+	}
+
+	if st := c.Stats(); st.Max != 2 || st.Length != 2 {
+		t.Fatalf("unexpected stats: %#+v", st)
+	}
+}
+
+func TestSchedulerBlackout(t *testing.T) {
+	c := New(1, time.Second)
+	s := NewScheduler(c, Schedule{
+		Location: noon(),
+		Default:  Profile{Max: 1, Per: time.Second},
+		Rules: []Rule{
+			{From: 11 * time.Hour, To: 13 * time.Hour, Profile: Profile{Blackout: true}},
+		},
+	})
+
+	if !c.IsPaused() || c.Allow() {
+		t.Fatalf("expected the limiter to be paused on blackout")
+	}
+
+	s.Close()
+	if c.IsPaused() || !c.Allow() {
+		t.Fatalf("expected the limiter to be resumed after close")
+	}
+}
@@ -0,0 +1,187 @@
+package chronos
+
+import (
+	"encoding"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Snapshotable is the interface which any state that can be saved and restored by
+// a `Snapshotter` should implement, the `C` is a Snapshotable.
+type Snapshotable interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// Snapshotter saves a `Snapshotable`, i.e a limiter, to a file
+// periodically and on `Close` and restores it on `Load`.
+//
+// It's useful when a process restarts and its limiter should not
+// start from zero while the remote service's window is still open.
+//
+// Use the `NewSnapshotter` or the `Restore` function.
+type Snapshotter struct {
+	Filename string
+	Interval time.Duration // zero means that the state is saved only on `Close`.
+
+	v       Snapshotable
+	mu      sync.Mutex // protects the file writes and the "err".
+	err     error      // the error of the last periodic save.
+	started bool
+	once    sync.Once
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// errNoSnapshotable is returned by a Snapshotter which is not created by `NewSnapshotter`.
+var errNoSnapshotable = errors.New("chronos: snapshotter: nothing to save, use NewSnapshotter")
+
+// NewSnapshotter returns a new Snapshotter which saves the "v"
+// to the "filename" file every "interval" duration,
+// the periodic save starts on `Start`.
+func NewSnapshotter(filename string, v Snapshotable, interval time.Duration) *Snapshotter {
+	return &Snapshotter{
+		Filename: filename,
+		Interval: interval,
+		v:        v,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Restore loads the "v" from the "filename" file, if exists,
+// and starts saving it every "interval" duration.
+// Callers should `Close` the returned Snapshotter on shutdown.
+//
+// Example Code:
+//
+//	c := chronos.New(5, time.Minute)
+//	s, err := chronos.Restore("limiter.state", c, 10*time.Second)
+//	if err != nil {
+//	    // handle error.
+//	}
+//	defer s.Close()
+func Restore(filename string, v Snapshotable, interval time.Duration) (*Snapshotter, error) {
+	s := NewSnapshotter(filename, v, interval)
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+
+	s.Start()
+	return s, nil
+}
+
+// Load restores the state from the file.
+// A missing file is not an error, the state is left untouched.
+func (s *Snapshotter) Load() error {
+	if s.v == nil {
+		return errNoSnapshotable
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := ioutil.ReadFile(s.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	return s.v.UnmarshalBinary(b)
+}
+
+// Save writes the current state to the file.
+// The file is replaced atomically so a crash while saving
+// does not leave a corrupted state behind.
+func (s *Snapshotter) Save() error {
+	if s.v == nil {
+		return errNoSnapshotable
+	}
+
+	b, err := s.v.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := ioutil.TempFile(filepath.Dir(s.Filename), filepath.Base(s.Filename)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	if _, err = f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, s.Filename)
+}
+
+// Start starts saving the state every `Interval` duration,
+// if `Interval` is zero then it does nothing.
+// The error of the last periodic save is returned by `Close`.
+func (s *Snapshotter) Start() {
+	if s.Interval <= 0 || s.started || s.stop == nil {
+		return
+	}
+
+	s.started = true
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				err := s.Save()
+				s.mu.Lock()
+				s.err = err
+				s.mu.Unlock()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the periodic saving, if started, and
+// saves the state for the last time.
+// It returns the error of that save or, if it succeeded,
+// the error of the last periodic save.
+func (s *Snapshotter) Close() error {
+	s.once.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+		}
+	})
+
+	if s.started {
+		<-s.done
+	}
+
+	if err := s.Save(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	err := s.err
+	s.mu.Unlock()
+	return err
+}
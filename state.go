@@ -0,0 +1,155 @@
+package chronos
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// state is the persistent part of a C,
+// it's used to save and restore a limiter across restarts.
+type state struct {
+	Max       uint32 `json:"max"`
+	Per       int64  `json:"per"` // nanoseconds.
+	Circle    uint64 `json:"circle"`
+	Length    uint32 `json:"length"`
+	LastAdded int64  `json:"lastAdded"` // unix nanoseconds.
+	// Offset is the offset of the limiter's window, if it's an `Aligner`.
+	Offset *int64 `json:"offset,omitempty"` // nanoseconds.
+	// Debt is the cost which is carried over to the next circles, see `Charge#Settle`.
+	Debt uint32 `json:"debt,omitempty"`
+}
+
+// stateVersion is the first byte of the binary form,
+// it should be incremented when the binary layout changes.
+// The version 2 appended the window's offset and the version 3 the debt,
+// the previous versions are still accepted.
+const stateVersion byte = 3
+
+const (
+	stateV1BinarySize = 1 + 4 + 8 + 8 + 4 + 8
+	stateV2BinarySize = stateV1BinarySize + 1 + 8 // has offset, offset.
+	stateBinarySize   = stateV2BinarySize + 4     // debt.
+)
+
+// ErrInvalidState is returned by `UnmarshalBinary` when
+// the data are not produced by a `MarshalBinary`.
+var ErrInvalidState = errors.New("chronos: invalid state data")
+
+func (c *C) getState() state {
+	c.mu.Lock()
+	s := state{
+		Max:       c.Max,
+		Per:       c.Per,
+		Circle:    c.Circle(),
+		Length:    c.getCurrentLength(),
+		LastAdded: c.getLastAdded(),
+		Debt:      c.debt,
+	}
+	if aligner, ok := c.Window.(Aligner); ok {
+		offset := int64(aligner.Offset())
+		s.Offset = &offset
+	}
+	c.mu.Unlock()
+	return s
+}
+
+// setState restores the "s" to the limiter.
+// A limiter which is already configured keeps its own Max and Per,
+// only the limiter's circle and its position are restored.
+func (c *C) setState(s state) {
+	c.mu.Lock()
+	if c.Max == 0 && c.Per == 0 {
+		c.Max = s.Max
+		c.Per = s.Per
+	}
+
+	// the last added time is a wall clock time, so it's still valid after a restart,
+	// the next `Acquire` will draw a new circle if the "per" duration passed in the meanwhile.
+	// However if the clock went backwards (or the state comes from a different machine)
+	// we can't trust it, be fair to the remote service and keep the window open from now.
+	lastAdded := s.LastAdded
+	if now := time.Now().UnixNano(); lastAdded > now {
+		lastAdded = now
+	}
+
+	c.setLastAdded(lastAdded)
+	c.setLength(s.Length)
+	c.setCircle(s.Circle)
+	c.debt = s.Debt
+
+	if aligner, ok := c.Window.(Aligner); ok && s.Offset != nil {
+		aligner.Align(time.Unix(0, *s.Offset))
+	}
+	c.mu.Unlock()
+}
+
+// MarshalBinary implements the `encoding.BinaryMarshaler` interface.
+// It encodes the configuration and the current state of the limiter.
+func (c *C) MarshalBinary() ([]byte, error) {
+	s := c.getState()
+
+	b := make([]byte, stateBinarySize)
+	b[0] = stateVersion
+	binary.BigEndian.PutUint32(b[1:], s.Max)
+	binary.BigEndian.PutUint64(b[5:], uint64(s.Per))
+	binary.BigEndian.PutUint64(b[13:], s.Circle)
+	binary.BigEndian.PutUint32(b[21:], s.Length)
+	binary.BigEndian.PutUint64(b[25:], uint64(s.LastAdded))
+	if s.Offset != nil {
+		b[33] = 1
+		binary.BigEndian.PutUint64(b[34:], uint64(*s.Offset))
+	}
+	binary.BigEndian.PutUint32(b[42:], s.Debt)
+	return b, nil
+}
+
+// UnmarshalBinary implements the `encoding.BinaryUnmarshaler` interface.
+// It restores a state which was encoded by `MarshalBinary`.
+//
+// See `Snapshotter` too.
+func (c *C) UnmarshalBinary(data []byte) error {
+	switch {
+	case len(data) == stateV1BinarySize && data[0] == 1:
+	case len(data) == stateV2BinarySize && data[0] == 2:
+	case len(data) == stateBinarySize && data[0] == stateVersion:
+	default:
+		return ErrInvalidState
+	}
+
+	s := state{
+		Max:       binary.BigEndian.Uint32(data[1:]),
+		Per:       int64(binary.BigEndian.Uint64(data[5:])),
+		Circle:    binary.BigEndian.Uint64(data[13:]),
+		Length:    binary.BigEndian.Uint32(data[21:]),
+		LastAdded: int64(binary.BigEndian.Uint64(data[25:])),
+	}
+
+	if len(data) >= stateV2BinarySize && data[33] == 1 {
+		offset := int64(binary.BigEndian.Uint64(data[34:]))
+		s.Offset = &offset
+	}
+	if len(data) == stateBinarySize {
+		s.Debt = binary.BigEndian.Uint32(data[42:])
+	}
+
+	c.setState(s)
+	return nil
+}
+
+// MarshalJSON implements the `json.Marshaler` interface.
+func (c *C) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.getState())
+}
+
+// UnmarshalJSON implements the `json.Unmarshaler` interface.
+func (c *C) UnmarshalJSON(data []byte) error {
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	c.setState(s)
+	return nil
+}
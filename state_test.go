@@ -0,0 +1,168 @@
+package chronos
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStateMarshal(t *testing.T) {
+	c := New(3, time.Second)
+	for i := 0; i < 3; i++ {
+		<-c.Acquire()
+	}
+
+	expected := c.getState()
+
+	b, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := new(C)
+	if err = restored.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := restored.getState(); got != expected {
+		t.Fatalf("binary: expected state %#+v but got %#+v", expected, got)
+	}
+
+	b, err = json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored = new(C)
+	if err = json.Unmarshal(b, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := restored.getState(); got != expected {
+		t.Fatalf("json: expected state %#+v but got %#+v", expected, got)
+	}
+
+	if err = restored.UnmarshalBinary(b); err != ErrInvalidState {
+		t.Fatalf("expected error %v but got %v", ErrInvalidState, err)
+	}
+}
+
+func TestStateRestoreKeepsConfiguration(t *testing.T) {
+	b, err := (&C{Max: 10, Per: int64(time.Minute), length: 4, lastAdded: time.Now().Add(time.Hour).UnixNano()}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(5, time.Second)
+	if err = c.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Max != 5 || c.Per != int64(time.Second) {
+		t.Fatalf("expected configuration to be kept but got max: %d, per: %d", c.Max, c.Per)
+	}
+
+	if got := c.getCurrentLength(); got != 4 {
+		t.Fatalf("expected length to be restored to 4 but got %d", got)
+	}
+
+	if lastAdded := c.getLastAdded(); lastAdded > time.Now().UnixNano() {
+		t.Fatalf("expected a last added time from the future to be adjusted to now")
+	}
+}
+
+func TestSnapshotter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chronos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "limiter.state")
+
+	per := 2 * time.Second
+	c := New(2, per)
+	s, err := Restore(filename, c, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-c.Acquire()
+	<-c.Acquire()
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// "restart".
+	c = New(2, per)
+	if _, err = Restore(filename, c, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.getCurrentLength(); got != 2 {
+		t.Fatalf("expected restored length to be 2 but got %d", got)
+	}
+
+	// the window is still open, so the next acquire should wait.
+	now := time.Now()
+	<-c.Acquire()
+	if since := time.Since(now); since < per/2 {
+		t.Fatalf("expected the acquire to wait for the restored window but fired after %s", since)
+	}
+}
+
+// flakySnapshotable fails to marshal while "fail" is set.
+type flakySnapshotable struct {
+	*C
+	fail int32
+}
+
+var errFlaky = errors.New("flaky")
+
+func (f *flakySnapshotable) MarshalBinary() ([]byte, error) {
+	if atomic.LoadInt32(&f.fail) == 1 {
+		return nil, errFlaky
+	}
+	return f.C.MarshalBinary()
+}
+
+func TestSnapshotterErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chronos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	v := &flakySnapshotable{C: New(2, time.Second), fail: 1}
+	s := NewSnapshotter(filepath.Join(dir, "limiter.state"), v, 5*time.Millisecond)
+	lastErr := func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.err
+	}
+
+	s.Start()
+	for lastErr() != errFlaky {
+		time.Sleep(time.Millisecond)
+	}
+
+	// a later periodic save succeeds.
+	atomic.StoreInt32(&v.fail, 0)
+	for lastErr() != nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err = s.Close(); err != nil {
+		t.Fatalf("expected no error after a successful save but got %v", err)
+	}
+
+	// a literal doesn't panic.
+	if err = new(Snapshotter).Close(); err != errNoSnapshotable {
+		t.Fatalf("expected errNoSnapshotable but got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package chronos
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of a limiter's state.
+type Stats struct {
+	Max        uint32        `json:"max"`
+	Per        time.Duration `json:"per"`
+	Circle     uint64        `json:"circle"`
+	Length     uint32        `json:"length"`     // operations counted in the current circle.
+	Remaining  uint32        `json:"remaining"`  // operations allowed before the limiter starts to wait.
+	ResetAfter time.Duration `json:"resetAfter"` // when the current circle will be finished, if full.
+	Debt       uint32        `json:"debt"`       // the cost which is carried over to the next circles, see `Charge#Settle`.
+	Paused     bool          `json:"paused"`
+	Draining   bool          `json:"draining"`
+	DryRun     bool          `json:"dryRun"`
+	// Warmth is how warm the limiter is, from 0 to 1, see `C.WarmUp`.
+	// The Remaining is counted from the "Max" of this warmth.
+	Warmth float64 `json:"warmth"`
+
+	Acquired uint64    `json:"acquired"` // operations allowed, cumulative.
+	Rejected uint64    `json:"rejected"` // operations not allowed or rejected, see `Observer.OnReject`, cumulative.
+	Waited   uint64    `json:"waited"`   // operations that had to wait before allowed, cumulative.
+	Waiting  int64     `json:"waiting"`  // operations that currently wait, the queue depth.
+	DryRuns  uint64    `json:"dryRuns"`  // operations that exceeded the limit but allowed in dry-run mode, cumulative.
+	Wait     Histogram `json:"wait"`     // the wait durations of the `Acquire`.
+}
+
+// Stats returns a snapshot of the limiter's state.
+func (c *C) Stats() Stats {
+	st := c.usage(time.Now().UnixNano())
+
+	st.Acquired = atomic.LoadUint64(&c.acquired)
+	st.Rejected = atomic.LoadUint64(&c.rejected)
+	st.Waited = atomic.LoadUint64(&c.waited)
+	st.Waiting = atomic.LoadInt64(&c.waiting)
+	st.DryRuns = atomic.LoadUint64(&c.dryRuns)
+	st.Wait = c.waitHistogram().snapshot()
+
+	return st
+}
+
+// Remaining returns the operations that are allowed before the limiter starts to wait,
+// it's the `Stats.Remaining` without the cost of the rest stats.
+func (c *C) Remaining() uint32 {
+	return c.usage(time.Now().UnixNano()).Remaining
+}
+
+// usage returns the stats of the current circle, without the cumulative ones.
+func (c *C) usage(now int64) Stats {
+	c.mu.RLock()
+	st := Stats{
+		Max:      c.Max,
+		Per:      time.Duration(c.Per),
+		Circle:   c.Circle(),
+		Length:   c.getCurrentLength(),
+		Paused:   c.paused,
+		Draining: c.draining,
+		DryRun:   c.dryRun,
+		Debt:     c.debt,
+		Warmth:   c.warmthAt(now),
+	}
+	max := c.limit(now)
+	carried := c.carried()
+	lastAdded := c.getLastAdded()
+	window := c.Window
+	c.mu.RUnlock()
+
+	// a circle which its time passed is not reset until the next `Acquire`,
+	// so report the values that the next `Acquire` will see.
+	if window != nil {
+		start, end := c.window(now)
+		st.Per = time.Duration(end - start)
+		st.ResetAfter = time.Duration(end - now)
+		if lastAdded != 0 && lastAdded < start {
+			st.Circle++
+			st.Length = carried
+			st.Debt -= carried
+		}
+	} else if lastAdded != 0 {
+		if elapsed := now - lastAdded; elapsed-int64(st.Per) > 0 {
+			st.Circle++
+			st.Length = carried
+			st.Debt -= carried
+		} else {
+			st.ResetAfter = st.Per - time.Duration(elapsed)
+		}
+	}
+
+	if st.Length < max {
+		st.Remaining = max - st.Length
+	}
+
+	return st
+}
@@ -1,5 +0,0 @@
-package chronos
-
-// I will make the package more powerful if will gain a lot of publicity and people actually use that,
-// but for now I don't need "strategies" so... maybe at the future.
-// type Strategy interface { }
@@ -0,0 +1,81 @@
+package chronos
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultColdFactor is the default `C.ColdFactor`.
+const DefaultColdFactor = 3
+
+// warmthAt returns how warm the limiter is at "current" time, from 0, cold, to 1, warm.
+// The limiter warms up while it's in use, until a circle after the last operation was asked,
+// and it cools down after that, at the same pace, see `C.WarmUp`.
+// It must be called under lock.
+func (c *C) warmthAt(current int64) float64 {
+	if c.WarmUp <= 0 {
+		return 1
+	}
+
+	warmth := c.warmth
+	if elapsed := current - c.warmedAt; c.warmedAt != 0 && elapsed > 0 {
+		active := c.lastAsked + int64(c.period(current)) - c.warmedAt
+		if active > elapsed {
+			active = elapsed
+		} else if active < 0 {
+			active = 0
+		}
+		idle := elapsed - active
+
+		warmth += float64(active-idle) / float64(c.WarmUp)
+	}
+
+	return math.Max(0, math.Min(1, warmth))
+}
+
+// ask records that operations are asked at "current" time, it warms the limiter up.
+// It must be called under lock.
+func (c *C) ask(current int64) {
+	if c.WarmUp <= 0 {
+		return
+	}
+
+	c.warmth = c.warmthAt(current)
+	c.warmedAt = current
+	c.lastAsked = current
+}
+
+// limit returns the maximum operations of a circle at "current" time,
+// it's the "Max" unless the limiter is warming up, see `C.WarmUp`.
+// It must be called under lock.
+func (c *C) limit(current int64) uint32 {
+	if c.WarmUp <= 0 {
+		return c.Max
+	}
+
+	factor := c.ColdFactor
+	if factor < 1 {
+		factor = DefaultColdFactor
+	}
+
+	cold := float64(c.Max) / factor
+	limit := uint32(math.Ceil(cold + (float64(c.Max)-cold)*c.warmthAt(current)))
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > c.Max {
+		limit = c.Max
+	}
+	return limit
+}
+
+// Warmth returns how warm the limiter is now, from 0, cold, to 1, warm,
+// it's always 1 without a `WarmUp`.
+func (c *C) Warmth() float64 {
+	now := time.Now().UnixNano()
+
+	c.mu.RLock()
+	warmth := c.warmthAt(now)
+	c.mu.RUnlock()
+	return warmth
+}
@@ -0,0 +1,61 @@
+package chronos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWarmUp(t *testing.T) {
+	c := New(9, time.Second)
+	c.WarmUp = 10 * time.Second
+
+	start := time.Now().UnixNano()
+	at := func(d time.Duration) int64 { return start + int64(d) }
+
+	// allowed returns the operations allowed at "current" time, up to the max.
+	allowed := func(current int64) (n uint32) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for n < c.Max {
+			if _, ok, _ := c.take(current, 1); !ok {
+				break
+			}
+			n++
+		}
+		return
+	}
+
+	if n := allowed(at(0)); n != 3 {
+		t.Fatalf("expected a cold limiter to allow the max divided by the cold factor but got %d", n)
+	}
+
+	// in use, every circle.
+	for i := 1; i <= 5; i++ {
+		allowed(at(time.Duration(i)*time.Second + 1))
+	}
+	c.mu.Lock()
+	limit := c.limit(at(5*time.Second + 1))
+	c.mu.Unlock()
+	if limit != 6 {
+		t.Fatalf("expected a half warm limiter to allow 6 but got %d", limit)
+	}
+
+	for i := 6; i <= 10; i++ {
+		allowed(at(time.Duration(i)*time.Second + 1))
+	}
+	if n := allowed(at(11 * time.Second)); n != 9 {
+		t.Fatalf("expected a warm limiter to allow the max but got %d", n)
+	}
+
+	// idle, it cools down after a circle.
+	if n := allowed(at(18 * time.Second)); n != 6 {
+		t.Fatalf("expected a half cool limiter to allow 6 but got %d", n)
+	}
+	if n := allowed(at(time.Minute)); n != 3 {
+		t.Fatalf("expected a cold limiter to allow 3 but got %d", n)
+	}
+
+	if warmth := New(1, time.Second).Warmth(); warmth != 1 {
+		t.Fatalf("expected a limiter without warm up to be warm but got %f", warmth)
+	}
+}
@@ -0,0 +1,211 @@
+package chronos
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Window aligns the circles of a limiter to fixed boundaries, see `C.Window`.
+// By default a circle starts with its first operation and lasts "per" time duration,
+// with a Window a circle starts and ends on the window's boundaries instead.
+type Window interface {
+	// Bounds returns the start, inclusive, and the end, exclusive,
+	// of the window which the "t" belongs to.
+	Bounds(t time.Time) (start, end time.Time)
+}
+
+// CalendarUnit is the unit of a `Calendar` window.
+type CalendarUnit uint8
+
+const (
+	// CalendarDay is a window from midnight to midnight.
+	CalendarDay CalendarUnit = iota
+	// CalendarWeek is a window from the midnight of the `Calendar.Weekday` to the next one.
+	CalendarWeek
+	// CalendarMonth is a window from the midnight of the `Calendar.Day` of a month
+	// to the same day of the next month.
+	CalendarMonth
+)
+
+// Calendar is a `Window` which is aligned to the wall clock of a time zone,
+// i.e "10000 operations per calendar day, reset at midnight UTC".
+// The days are days of the wall clock, a day can be 23 or 25 hours long across a DST change,
+// and the months have their actual length.
+type Calendar struct {
+	Unit CalendarUnit
+	// Weekday is the first day of a `CalendarWeek`, defaults to Sunday.
+	Weekday time.Weekday
+	// Day is the first day of a `CalendarMonth`, i.e the billing day, defaults to 1.
+	// On a month with fewer days the last day of the month is used.
+	Day int
+	// Location is the time zone of the wall clock, defaults to UTC.
+	Location *time.Location
+}
+
+var _ Window = Calendar{}
+
+// Daily returns a `Calendar` window of a day in the "loc" time zone.
+func Daily(loc *time.Location) Calendar {
+	return Calendar{Unit: CalendarDay, Location: loc}
+}
+
+// Weekly returns a `Calendar` window of a week, which starts on "weekday", in the "loc" time zone.
+func Weekly(weekday time.Weekday, loc *time.Location) Calendar {
+	return Calendar{Unit: CalendarWeek, Weekday: weekday, Location: loc}
+}
+
+// Monthly returns a `Calendar` window of a month, which starts on the "day" of the month,
+// in the "loc" time zone.
+func Monthly(day int, loc *time.Location) Calendar {
+	return Calendar{Unit: CalendarMonth, Day: day, Location: loc}
+}
+
+// Bounds implements the `Window` interface.
+func (cal Calendar) Bounds(t time.Time) (start, end time.Time) {
+	loc := cal.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	t = t.In(loc)
+	y, m, d := t.Date()
+
+	switch cal.Unit {
+	case CalendarWeek:
+		d -= (int(t.Weekday()) - int(cal.Weekday) + 7) % 7
+		return time.Date(y, m, d, 0, 0, 0, 0, loc), time.Date(y, m, d+7, 0, 0, 0, 0, loc)
+	case CalendarMonth:
+		start = cal.monthStart(y, m, loc)
+		if t.Before(start) {
+			return cal.monthStart(y, m-1, loc), start
+		}
+		return start, cal.monthStart(y, m+1, loc)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc), time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+}
+
+// monthStart returns the midnight of the `Day` of the "m" month,
+// the "m" can be out of the 1-12 range.
+func (cal Calendar) monthStart(y int, m time.Month, loc *time.Location) time.Time {
+	first := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	y, m = first.Year(), first.Month()
+
+	day := cal.Day
+	if day < 1 {
+		day = 1
+	}
+
+	// the day zero of the next month is the last day of this one.
+	if last := time.Date(y, m+1, 0, 0, 0, 0, 0, loc).Day(); day > last {
+		day = last
+	}
+
+	return time.Date(y, m, day, 0, 0, 0, 0, loc)
+}
+
+// Aligner is a `Window` which can be re-aligned at runtime, see `C#AlignTo`.
+type Aligner interface {
+	Window
+	// Align moves the boundaries of the windows so one of them is at the "boundary" time.
+	Align(boundary time.Time)
+	// Offset returns the distance of the boundaries from the unix epoch.
+	Offset() time.Duration
+}
+
+// Fixed is a `Window` of a fixed size which is aligned to the unix epoch plus an offset,
+// i.e `NewFixed(time.Minute, 0)` starts its windows at the top of each minute,
+// as many APIs reset their counters. It can be re-aligned at runtime, see `C#AlignTo`.
+type Fixed struct {
+	size   int64
+	offset int64 // atomic, in the range [0, size).
+}
+
+var _ Aligner = (*Fixed)(nil)
+
+// NewFixed returns a new Fixed window of "size", its boundaries are
+// the unix epoch plus the "offset" plus any multiple of the "size".
+func NewFixed(size, offset time.Duration) *Fixed {
+	if size <= 0 {
+		size = 1
+	}
+
+	return &Fixed{size: int64(size), offset: mod(int64(offset), int64(size))}
+}
+
+// mod is the modulo which is never negative.
+func mod(a, b int64) int64 {
+	m := a % b
+	if m < 0 {
+		m += b
+	}
+	return m
+}
+
+// Size returns the size of the windows.
+func (f *Fixed) Size() time.Duration {
+	return time.Duration(f.size)
+}
+
+// Offset implements the `Aligner` interface.
+func (f *Fixed) Offset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&f.offset))
+}
+
+// Align implements the `Aligner` interface.
+func (f *Fixed) Align(boundary time.Time) {
+	atomic.StoreInt64(&f.offset, mod(boundary.UnixNano(), f.size))
+}
+
+// Bounds implements the `Window` interface.
+func (f *Fixed) Bounds(t time.Time) (start, end time.Time) {
+	n := t.UnixNano()
+	s := n - mod(n-atomic.LoadInt64(&f.offset), f.size)
+	return time.Unix(0, s), time.Unix(0, s+f.size)
+}
+
+// ErrNotAligner is returned by `C#AlignTo` when the limiter's `Window` is not an `Aligner`.
+var ErrNotAligner = errors.New("chronos: the limiter's window can't be aligned")
+
+// AlignTo re-aligns the limiter's `Window` so the current window ends at the "reset" time,
+// i.e from the "X-RateLimit-Reset" header of a response, so the local circles
+// match the ones that the server counts. The window must be an `Aligner`, i.e a `Fixed`.
+//
+// The operations that are counted in the current circle are kept,
+// unless the re-aligned window has already started after them.
+func (c *C) AlignTo(reset time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	aligner, ok := c.Window.(Aligner)
+	if !ok {
+		return ErrNotAligner
+	}
+
+	aligner.Align(reset)
+	return nil
+}
+
+// NewWindow returns a new C which allows "max" operations per "w" window,
+// i.e `NewWindow(10000, Daily(time.UTC))`.
+func NewWindow(max uint32, w Window) *C {
+	return &C{Max: max, Window: w}
+}
+
+// window returns the bounds, in unix nanoseconds, of the limiter's `Window` at the "current" time.
+func (c *C) window(current int64) (start, end int64) {
+	s, e := c.Window.Bounds(time.Unix(0, current))
+	return s.UnixNano(), e.UnixNano()
+}
+
+// period returns the duration of the circle at the "current" time,
+// it must be called under lock.
+func (c *C) period(current int64) time.Duration {
+	if c.Window == nil {
+		return time.Duration(c.Per)
+	}
+
+	start, end := c.window(current)
+	return time.Duration(end - start)
+}
@@ -0,0 +1,166 @@
+package chronos
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // the tests should not depend on the system's time zone database.
+)
+
+func TestCalendarBounds(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := func(loc *time.Location, y int, m time.Month, d, h int) time.Time {
+		return time.Date(y, m, d, h, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name       string
+		cal        Calendar
+		t          time.Time
+		start, end time.Time
+	}{
+		{"day", Daily(nil), date(time.UTC, 2026, 3, 15, 13), date(time.UTC, 2026, 3, 15, 0), date(time.UTC, 2026, 3, 16, 0)},
+		// 2026-03-08 is 23 hours long in New York and the 2026-11-01 is 25 hours long.
+		{"day dst start", Daily(newYork), date(newYork, 2026, 3, 8, 12), date(newYork, 2026, 3, 8, 0), date(newYork, 2026, 3, 9, 0)},
+		{"day dst end", Daily(newYork), date(newYork, 2026, 11, 1, 23), date(newYork, 2026, 11, 1, 0), date(newYork, 2026, 11, 2, 0)},
+		// a UTC time which is the previous day in New York.
+		{"day location", Daily(newYork), date(time.UTC, 2026, 3, 15, 2), date(newYork, 2026, 3, 14, 0), date(newYork, 2026, 3, 15, 0)},
+		// 2026-03-18 is a Wednesday.
+		{"week", Weekly(time.Monday, nil), date(time.UTC, 2026, 3, 18, 5), date(time.UTC, 2026, 3, 16, 0), date(time.UTC, 2026, 3, 23, 0)},
+		{"week start", Weekly(time.Wednesday, nil), date(time.UTC, 2026, 3, 18, 0), date(time.UTC, 2026, 3, 18, 0), date(time.UTC, 2026, 3, 25, 0)},
+		{"month", Monthly(1, nil), date(time.UTC, 2026, 2, 28, 23), date(time.UTC, 2026, 2, 1, 0), date(time.UTC, 2026, 3, 1, 0)},
+		{"month year", Monthly(0, nil), date(time.UTC, 2026, 12, 31, 23), date(time.UTC, 2026, 12, 1, 0), date(time.UTC, 2027, 1, 1, 0)},
+		{"billing month", Monthly(15, nil), date(time.UTC, 2026, 3, 10, 0), date(time.UTC, 2026, 2, 15, 0), date(time.UTC, 2026, 3, 15, 0)},
+		// the 31st on a month with fewer days is its last day.
+		{"billing month short", Monthly(31, nil), date(time.UTC, 2026, 3, 1, 0), date(time.UTC, 2026, 2, 28, 0), date(time.UTC, 2026, 3, 31, 0)},
+		{"billing month leap", Monthly(30, nil), date(time.UTC, 2028, 2, 29, 12), date(time.UTC, 2028, 2, 29, 0), date(time.UTC, 2028, 3, 30, 0)},
+	}
+
+	for _, tt := range tests {
+		start, end := tt.cal.Bounds(tt.t)
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Fatalf("[%s] expected [%s, %s) but got [%s, %s)", tt.name, tt.start, tt.end, start, end)
+		}
+	}
+
+	if start, end := Daily(newYork).Bounds(date(newYork, 2026, 11, 1, 12)); end.Sub(start) != 25*time.Hour {
+		t.Fatalf("expected the day of the DST end to be 25 hours long but got %s", end.Sub(start))
+	}
+}
+
+// fixedWindow is a window of "size" from the unix epoch, it's aligned like a calendar.
+type fixedWindow time.Duration
+
+func (w fixedWindow) Bounds(t time.Time) (time.Time, time.Time) {
+	start := t.Truncate(time.Duration(w))
+	return start, start.Add(time.Duration(w))
+}
+
+func TestWindow(t *testing.T) {
+	size := 300 * time.Millisecond
+	c := NewWindow(2, fixedWindow(size))
+
+	// start at the beginning of a window.
+	time.Sleep(time.Until(time.Now().Truncate(size).Add(size)))
+	windowEnd := time.Now().Truncate(size).Add(size)
+
+	if !c.Allow() || !c.Allow() {
+		t.Fatalf("expected the first two operations to be allowed")
+	}
+
+	err := c.Take()
+	limited, ok := err.(*LimitError)
+	if !ok {
+		t.Fatalf("expected a *LimitError but got %v", err)
+	}
+
+	if limited.Per != size || limited.RetryAfter <= 0 || limited.RetryAfter > time.Until(windowEnd)+10*time.Millisecond {
+		t.Fatalf("expected to retry at the end of the window but got %#+v", limited)
+	}
+
+	if st := c.Stats(); st.Per != size || st.Length != 2 || st.Remaining != 0 || st.ResetAfter > size {
+		t.Fatalf("unexpected stats: %#+v", st)
+	}
+
+	// the next operation is allowed on the window's boundary,
+	// not "per" after the first operation of the circle.
+	<-c.Acquire()
+	if now := time.Now(); now.Before(windowEnd) || now.After(windowEnd.Add(size/2)) {
+		t.Fatalf("expected the operation to be allowed at %s but it was at %s", windowEnd, now)
+	}
+
+	if st := c.Stats(); st.Circle != 1 || st.Length != 1 {
+		t.Fatalf("unexpected stats on the next window: %#+v", st)
+	}
+}
+
+func TestFixed(t *testing.T) {
+	w := NewFixed(time.Minute, 0)
+	start, end := w.Bounds(time.Date(2026, 3, 15, 10, 41, 27, 0, time.UTC))
+	if expected := time.Date(2026, 3, 15, 10, 41, 0, 0, time.UTC); !start.Equal(expected) || end.Sub(start) != time.Minute {
+		t.Fatalf("expected the window to start at the top of the minute but got [%s, %s)", start, end)
+	}
+
+	// the server resets at :15 of each minute.
+	c := NewWindow(10, w)
+	if err := c.AlignTo(time.Date(2026, 3, 15, 10, 42, 15, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	if offset := w.Offset(); offset != 15*time.Second {
+		t.Fatalf("expected offset 15s but got %s", offset)
+	}
+
+	start, _ = w.Bounds(time.Date(2026, 3, 15, 10, 41, 10, 0, time.UTC))
+	if expected := time.Date(2026, 3, 15, 10, 40, 15, 0, time.UTC); !start.Equal(expected) {
+		t.Fatalf("expected the window to start at %s but got %s", expected, start)
+	}
+
+	// negative offsets and times before the epoch.
+	if start, _ = NewFixed(time.Minute, -time.Second).Bounds(time.Unix(-30, 0)); start.Unix() != -61 {
+		t.Fatalf("expected the window to start at -61 but got %d", start.Unix())
+	}
+
+	if err := New(1, time.Second).AlignTo(time.Now()); err != ErrNotAligner {
+		t.Fatalf("expected ErrNotAligner but got %v", err)
+	}
+}
+
+func TestFixedState(t *testing.T) {
+	c := NewWindow(10, NewFixed(time.Minute, 0))
+	c.AlignTo(time.Unix(0, int64(20*time.Second)))
+	c.Allow()
+
+	b, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewFixed(time.Minute, 0)
+	restored := NewWindow(10, w)
+	if err = restored.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if offset := w.Offset(); offset != 20*time.Second {
+		t.Fatalf("expected the offset to be restored but got %s", offset)
+	}
+
+	if st := restored.Stats(); st.Length != 1 {
+		t.Fatalf("expected the length to be restored but got %d", st.Length)
+	}
+
+	// the version 1 has no offset.
+	v1 := append([]byte{1}, b[1:stateV1BinarySize]...)
+	restored = NewWindow(10, NewFixed(time.Minute, 0))
+	if err = restored.UnmarshalBinary(v1); err != nil {
+		t.Fatalf("expected the version 1 to be accepted but got %v", err)
+	}
+
+	if st := restored.Stats(); st.Length != 1 {
+		t.Fatalf("expected the length to be restored from the version 1 but got %d", st.Length)
+	}
+}